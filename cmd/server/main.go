@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"flag"
 	"log"
 	"net/http"
 	"os"
@@ -11,37 +12,89 @@ import (
 	_ "github.com/lib/pq"
 
 	httpserver "github.com/ynsssss/pr-manager/internal/api/http"
+	memoryrepo "github.com/ynsssss/pr-manager/internal/repository/memory"
 	sqlrepo "github.com/ynsssss/pr-manager/internal/repository/sql"
 	"github.com/ynsssss/pr-manager/internal/service"
 )
 
 func main() {
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		log.Fatal("DATABASE_URL is not set")
-	}
+	storage := flag.String("storage", "postgres", "storage backend: postgres or memory")
+	reviewerStrategy := flag.String(
+		"reviewer-strategy",
+		"random",
+		"reviewer assignment strategy: random, least-loaded or round-robin",
+	)
+	outOfOfficeInterval := flag.Duration(
+		"out-of-office-interval",
+		time.Minute,
+		"how often reviews of users whose out of office started are reassigned",
+	)
+	flag.Parse()
 
-	db, err := sql.Open("postgres", databaseURL)
-	if err != nil {
-		log.Fatalf("failed to open DB connection: %v", err)
+	var (
+		userRepo     service.UserRepository
+		teamRepo     service.TeamRepository
+		prRepo       service.PullRequestRepository
+		txManager    service.TxManager
+		rotationRepo service.RotationRepository
+		auditRepo    service.AuditRepository
+	)
+
+	switch *storage {
+	case "postgres":
+		db := openPostgres()
+		defer db.Close()
+
+		userRepo = sqlrepo.NewUserRepository(db)
+		teamRepo = sqlrepo.NewTeamRepository(db)
+		prRepo = sqlrepo.NewPullRequestRepository(db)
+		txManager = sqlrepo.NewTxManager(db)
+		rotationRepo = sqlrepo.NewRotationRepository(db)
+		auditRepo = sqlrepo.NewAuditRepository(db)
+	case "memory":
+		db := memoryrepo.NewDB()
+
+		userRepo = memoryrepo.NewUserRepository(db)
+		teamRepo = memoryrepo.NewTeamRepository(db)
+		prRepo = memoryrepo.NewPullRequestRepository(db)
+		txManager = memoryrepo.NewTxManager(db)
+		rotationRepo = memoryrepo.NewRotationRepository(db)
+		auditRepo = memoryrepo.NewAuditRepository(db)
+	default:
+		log.Fatalf("unknown storage %q, expected postgres or memory", *storage)
 	}
-	defer db.Close()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := db.PingContext(ctx); err != nil {
-		log.Fatalf("cannot connect to database: %v", err)
+	var strategy service.ReviewerStrategy
+	switch *reviewerStrategy {
+	case "random":
+		strategy = service.NewRandomStrategy()
+	case "least-loaded":
+		strategy = service.NewLeastLoadedStrategy(prRepo)
+	case "round-robin":
+		strategy = service.NewRoundRobinStrategy(rotationRepo)
+	default:
+		log.Fatalf(
+			"unknown reviewer strategy %q, expected random, least-loaded or round-robin",
+			*reviewerStrategy,
+		)
 	}
 
-	userRepo := sqlrepo.NewUserRepository(db)
-	teamRepo := sqlrepo.NewTeamRepository(db)
-	prRepo := sqlrepo.NewPullRequestRepository(db)
+	auditService := service.NewAuditService(auditRepo)
+	prService := service.NewPullRequestService(prRepo, userRepo, teamRepo, txManager, strategy, auditService)
+	userService := service.NewUserService(userRepo, txManager, prService, auditService)
+	teamService := service.NewTeamService(teamRepo, userRepo, prRepo, txManager, prService, auditService)
 
-	userService := service.NewUserService(userRepo)
-	teamService := service.NewTeamService(teamRepo, userRepo)
-	prService := service.NewPullRequestService(prRepo, userRepo, teamRepo)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go userService.RunOutOfOfficeJob(ctx, *outOfOfficeInterval)
 
-	router := httpserver.NewRouter(userService, teamService, prService)
+	router := httpserver.NewRouter(
+		userService,
+		teamService,
+		prService,
+		auditService,
+		os.Getenv("ADMIN_TOKEN"),
+	)
 
 	server := &http.Server{
 		Addr:         ":8080",
@@ -50,8 +103,28 @@ func main() {
 		WriteTimeout: 10 * time.Second,
 	}
 
-	log.Println("Starting PR Manager service on :8080")
+	log.Printf("Starting PR Manager service on :8080 (storage: %s)", *storage)
 	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("server error: %v", err)
 	}
 }
+
+func openPostgres() *sql.DB {
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal("DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		log.Fatalf("failed to open DB connection: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		log.Fatalf("cannot connect to database: %v", err)
+	}
+
+	return db
+}
@@ -3,6 +3,6 @@ module github.com/ynsssss/pr-manager
 go 1.24.2
 
 require (
-	github.com/gorilla/mux v1.8.1 // indirect
-	github.com/lib/pq v1.10.9 // indirect
+	github.com/gorilla/mux v1.8.1
+	github.com/lib/pq v1.10.9
 )
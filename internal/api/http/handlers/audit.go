@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ynsssss/pr-manager/internal/domain"
+	"github.com/ynsssss/pr-manager/internal/service"
+)
+
+type AuditHandler struct {
+	service *service.AuditService
+}
+
+func NewAuditHandler(service *service.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// GET /audit
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := domain.AuditFilter{
+		EntityType: domain.AuditEntityType(query.Get("entity_type")),
+		EntityID:   query.Get("entity_id"),
+	}
+
+	var err error
+	if filter.Limit, filter.After, err = parsePage(query); err != nil {
+		sendError(w, err)
+		return
+	}
+	if filter.From, err = parseTime(query, "from"); err != nil {
+		sendError(w, err)
+		return
+	}
+	if filter.To, err = parseTime(query, "to"); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	page, err := h.service.ListEvents(r.Context(), filter)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, 200, page)
+}
+
+// WithActor attributes the changes made by a request to
+// the user named in its X-Actor header
+func WithActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := service.WithActor(r.Context(), r.Header.Get("X-Actor"))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -15,14 +15,15 @@ type ErrorResponse struct {
 	Error struct {
 		Code    string `json:"code"`
 		Message string `json:"message"`
+		Details any    `json:"details,omitempty"`
 	} `json:"error"`
 }
 
-
 // TODO: create custom error
 // TODO: fix status codes
 func sendError(w http.ResponseWriter, err error) {
 	resp := ErrorResponse{}
+	var mergeBlocked *domain.MergeBlockedError
 	switch {
 	case domain.IsValidationError(err):
 		// TODO: add code
@@ -34,6 +35,11 @@ func sendError(w http.ResponseWriter, err error) {
 		resp.Error.Message = "team_name already exists"
 		writeJSON(w, http.StatusBadRequest, resp)
 
+	case errors.Is(err, domain.ErrTeamHasOpenPRs):
+		resp.Error.Code = "TEAM_HAS_OPEN_PRS"
+		resp.Error.Message = "team members have open pull requests"
+		writeJSON(w, http.StatusConflict, resp)
+
 	case errors.Is(err, domain.ErrPRExists):
 		resp.Error.Code = "PR_EXISTS"
 		resp.Error.Message = "PR id already exists"
@@ -44,6 +50,16 @@ func sendError(w http.ResponseWriter, err error) {
 		resp.Error.Message = "cannot reassign on merged PR"
 		writeJSON(w, http.StatusConflict, resp)
 
+	case errors.Is(err, domain.ErrPRNotOpen):
+		resp.Error.Code = "PR_NOT_OPEN"
+		resp.Error.Message = "PR is not open"
+		writeJSON(w, http.StatusConflict, resp)
+
+	case errors.Is(err, domain.ErrInvalidTransition):
+		resp.Error.Code = "INVALID_TRANSITION"
+		resp.Error.Message = err.Error()
+		writeJSON(w, http.StatusConflict, resp)
+
 	case errors.Is(err, domain.ErrNotAssigned):
 		resp.Error.Code = "NOT_ASSIGNED"
 		resp.Error.Message = "reviewer is not assigned to this PR"
@@ -54,6 +70,22 @@ func sendError(w http.ResponseWriter, err error) {
 		resp.Error.Message = "no active replacement candidate in team"
 		writeJSON(w, http.StatusConflict, resp)
 
+	case errors.Is(err, domain.ErrReviewersAtCapacity):
+		resp.Error.Code = "AT_CAPACITY"
+		resp.Error.Message = "not enough reviewers below their review capacity"
+		writeJSON(w, http.StatusConflict, resp)
+
+	case errors.As(err, &mergeBlocked):
+		resp.Error.Code = "MERGE_BLOCKED"
+		resp.Error.Message = "merge requirements are not met"
+		resp.Error.Details = mergeBlocked
+		writeJSON(w, http.StatusConflict, resp)
+
+	case errors.Is(err, domain.ErrForbidden):
+		resp.Error.Code = "FORBIDDEN"
+		resp.Error.Message = "operation is not allowed"
+		writeJSON(w, http.StatusForbidden, resp)
+
 	case errors.Is(err, domain.ErrNotFound):
 		resp.Error.Code = "NOT_FOUND"
 		resp.Error.Message = "resource not found"
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/ynsssss/pr-manager/internal/domain"
 	"github.com/ynsssss/pr-manager/internal/service"
@@ -10,10 +13,15 @@ import (
 
 type PRHandler struct {
 	svc *service.PullRequestService
+	// adminToken authorizes forced merges, they are disabled when it's empty
+	adminToken string
 }
 
-func NewPRHandler(svc *service.PullRequestService) *PRHandler {
-	return &PRHandler{svc: svc}
+func NewPRHandler(svc *service.PullRequestService, adminToken string) *PRHandler {
+	return &PRHandler{
+		svc:        svc,
+		adminToken: adminToken,
+	}
 }
 
 func (h *PRHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -23,7 +31,7 @@ func (h *PRHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pr, err := h.svc.Create(r.Context(), req.ID, req.Name, req.AuthorID)
+	pr, err := h.svc.Create(r.Context(), req.ID, req.Name, req.AuthorID, req.Draft)
 	if err != nil {
 		sendError(w, err)
 		return
@@ -35,6 +43,7 @@ type CreateRequest struct {
 	ID       string `json:"pull_request_id"`
 	Name     string `json:"pull_request_name"`
 	AuthorID string `json:"author_id"`
+	Draft    bool   `json:"draft"`
 }
 
 func (h *PRHandler) Merge(w http.ResponseWriter, r *http.Request) {
@@ -44,7 +53,20 @@ func (h *PRHandler) Merge(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pr, err := h.svc.Merge(r.Context(), req.PrId)
+	var forcedBy string
+	if req.Force {
+		if !h.isAdmin(r) {
+			sendError(w, domain.ErrForbidden)
+			return
+		}
+		if req.ForcedBy == "" {
+			sendError(w, domain.ErrEmptyForcedBy)
+			return
+		}
+		forcedBy = req.ForcedBy
+	}
+
+	pr, err := h.svc.Merge(r.Context(), req.PrId, forcedBy)
 	if err != nil {
 		sendError(w, err)
 		return
@@ -55,6 +77,51 @@ func (h *PRHandler) Merge(w http.ResponseWriter, r *http.Request) {
 
 type mergeRequest struct {
 	PrId string `json:"pull_request_id"`
+	// Force bypasses the merge policy, it requires the admin token
+	Force    bool   `json:"force"`
+	ForcedBy string `json:"forced_by"`
+}
+
+func (h *PRHandler) isAdmin(r *http.Request) bool {
+	token := r.Header.Get("X-Admin-Token")
+	return h.adminToken != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1
+}
+
+func (h *PRHandler) Close(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.svc.Close)
+}
+
+func (h *PRHandler) Reopen(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.svc.Reopen)
+}
+
+func (h *PRHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.svc.MarkReady)
+}
+
+func (h *PRHandler) changeStatus(
+	w http.ResponseWriter,
+	r *http.Request,
+	change func(ctx context.Context, prID string) (*domain.PullRequest, error),
+) {
+	var req statusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	pr, err := change(r.Context(), req.PrId)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, 200, pr)
+}
+
+type statusRequest struct {
+	PrId string `json:"pull_request_id"`
 }
 
 func (h *PRHandler) Reassign(w http.ResponseWriter, r *http.Request) {
@@ -86,3 +153,79 @@ type reassignResponse struct {
 	Pr           domain.PullRequest `json:"pr"`
 	ReplacedById string             `json:"replaced_by"`
 }
+
+func (h *PRHandler) Review(w http.ResponseWriter, r *http.Request) {
+	var req reviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	pr, err := h.svc.Review(r.Context(), req.PrId, req.ReviewerId, req.Verdict)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, 200, pr)
+}
+
+type reviewRequest struct {
+	PrId       string               `json:"pull_request_id"`
+	ReviewerId string               `json:"reviewer_id"`
+	Verdict    domain.ReviewVerdict `json:"verdict"`
+}
+
+// GET /pullRequest/get
+func (h *PRHandler) Get(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+
+	details, err := h.svc.GetDetails(r.Context(), prID)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSONWithETag(w, r, details)
+}
+
+// GET /pullRequest/list
+func (h *PRHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := domain.PullRequestFilter{
+		Status:     domain.PRStatus(query.Get("status")),
+		AuthorID:   query.Get("author_id"),
+		ReviewerID: query.Get("reviewer_id"),
+		TeamName:   query.Get("team_name"),
+	}
+
+	var err error
+	filter.Limit, filter.After, err = parsePage(query)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+	for _, param := range []struct {
+		key string
+		dst **time.Time
+	}{
+		{"created_from", &filter.CreatedFrom},
+		{"created_to", &filter.CreatedTo},
+		{"merged_from", &filter.MergedFrom},
+		{"merged_to", &filter.MergedTo},
+	} {
+		if *param.dst, err = parseTime(query, param.key); err != nil {
+			sendError(w, err)
+			return
+		}
+	}
+
+	page, err := h.svc.ListPullRequests(r.Context(), filter)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, 200, page)
+}
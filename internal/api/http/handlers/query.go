@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ynsssss/pr-manager/internal/domain"
+)
+
+// parsePage reads the limit and cursor query parameters of list endpoints
+func parsePage(query url.Values) (int, *domain.Cursor, error) {
+	limit := domain.DefaultPageLimit
+	if s := query.Get("limit"); s != "" {
+		var err error
+		limit, err = strconv.Atoi(s)
+		if err != nil {
+			return 0, nil, domain.ErrInvalidPageLimit
+		}
+	}
+
+	if s := query.Get("cursor"); s != "" {
+		cursor, err := domain.DecodeCursor(s)
+		if err != nil {
+			return 0, nil, err
+		}
+		return limit, cursor, nil
+	}
+	return limit, nil, nil
+}
+
+// parseTime reads an optional RFC 3339 timestamp
+func parseTime(query url.Values, key string) (*time.Time, error) {
+	s := query.Get(key)
+	if s == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, domain.NewValidationError(key + " is not an RFC 3339 timestamp")
+	}
+	return &t, nil
+}
+
+// parseBool reads an optional boolean
+func parseBool(query url.Values, key string) (*bool, error) {
+	s := query.Get(key)
+	if s == "" {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, domain.NewValidationError(key + " is not a boolean")
+	}
+	return &b, nil
+}
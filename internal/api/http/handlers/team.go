@@ -18,7 +18,8 @@ func NewTeamHandler(service *service.TeamService) *TeamHandler {
 
 // POST /team/add
 func (h *TeamHandler) Add(w http.ResponseWriter, r *http.Request) {
-	var teamRequest domain.Team
+	// settings omitted from the payload keep their defaults
+	teamRequest := domain.Team{TeamSettings: domain.DefaultTeamSettings()}
 
 	if err := json.NewDecoder(r.Body).Decode(&teamRequest); err != nil {
 		sendError(w, err)
@@ -40,7 +41,12 @@ func (h *TeamHandler) GetByName(w http.ResponseWriter, r *http.Request) {
 	queryParams := r.URL.Query()
 
 	teamName := queryParams.Get("team_name")
-	team, err := h.service.GetByName(r.Context(), teamName)
+	getTeam := h.service.GetByName
+	if queryParams.Get("recursive") == "true" {
+		getTeam = h.service.GetWithSubteams
+	}
+
+	team, err := getTeam(r.Context(), teamName)
 	if err != nil {
 		sendError(w, err)
 		return
@@ -49,3 +55,328 @@ func (h *TeamHandler) GetByName(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, 200, team)
 	return
 }
+
+// GET /team/list
+func (h *TeamHandler) List(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.ListTeams(r.Context())
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, 200, listTeamsResponse{Teams: stats})
+}
+
+type listTeamsResponse struct {
+	Teams []domain.TeamStats `json:"teams"`
+}
+
+// POST /team/settings
+func (h *TeamHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req teamSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	team, err := h.service.UpdateSettings(
+		r.Context(),
+		req.TeamName,
+		func(settings *domain.TeamSettings) {
+			if req.MinReviewers != nil {
+				settings.MinReviewers = *req.MinReviewers
+			}
+			if req.MaxReviewers != nil {
+				settings.MaxReviewers = *req.MaxReviewers
+			}
+			if req.RequiredApprovals != nil {
+				settings.RequiredApprovals = *req.RequiredApprovals
+			}
+			if req.FallbackTeams != nil {
+				settings.FallbackTeams = *req.FallbackTeams
+			}
+			if req.DefaultMaxOpenReviews != nil {
+				settings.DefaultMaxOpenReviews = *req.DefaultMaxOpenReviews
+			}
+			if req.CapacityPolicy != nil {
+				settings.CapacityPolicy = *req.CapacityPolicy
+			}
+		},
+	)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, 200, team)
+}
+
+// teamSettingsRequest leaves the settings that are not set untouched
+type teamSettingsRequest struct {
+	TeamName     string `json:"team_name"`
+	MinReviewers *int   `json:"min_reviewers"`
+	MaxReviewers *int   `json:"max_reviewers"`
+
+	RequiredApprovals *int      `json:"required_approvals"`
+	FallbackTeams     *[]string `json:"fallback_teams"`
+
+	DefaultMaxOpenReviews *int                   `json:"default_max_open_reviews"`
+	CapacityPolicy        *domain.CapacityPolicy `json:"capacity_policy"`
+}
+
+// POST /team/deactivateUsers
+func (h *TeamHandler) DeactivateUsers(w http.ResponseWriter, r *http.Request) {
+	var req deactivateUsersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	users, reassignments, err := h.service.DeactivateUsers(r.Context(), req.TeamName, req.UserIDs)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	response := deactivateUsersResponse{
+		TeamName:      req.TeamName,
+		Users:         users,
+		Reassignments: reassignments,
+	}
+
+	writeJSON(w, 200, response)
+}
+
+type deactivateUsersRequest struct {
+	TeamName string   `json:"team_name"`
+	UserIDs  []string `json:"user_ids"`
+}
+
+type deactivateUsersResponse struct {
+	TeamName      string                `json:"team_name"`
+	Users         []domain.User         `json:"users"`
+	Reassignments []domain.Reassignment `json:"reassignments"`
+}
+
+// POST /team/addMembers
+func (h *TeamHandler) AddMembers(w http.ResponseWriter, r *http.Request) {
+	req := addMembersRequest{ReviewPolicy: domain.ReviewsKeep}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	team, reassignments, err := h.service.AddMembers(
+		r.Context(),
+		req.TeamName,
+		req.Members,
+		req.ReviewPolicy,
+	)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, 200, membershipResponse{Team: team, Reassignments: reassignments})
+}
+
+// addMembersRequest applies the review policy to the members
+// who are moved from another team
+type addMembersRequest struct {
+	TeamName     string              `json:"team_name"`
+	Members      []domain.TeamMember `json:"members"`
+	ReviewPolicy domain.ReviewPolicy `json:"review_policy"`
+}
+
+// POST /team/removeMembers
+func (h *TeamHandler) RemoveMembers(w http.ResponseWriter, r *http.Request) {
+	req := removeMembersRequest{ReviewPolicy: domain.ReviewsKeep}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	team, reassignments, err := h.service.RemoveMembers(
+		r.Context(),
+		req.TeamName,
+		req.UserIDs,
+		req.ReviewPolicy,
+	)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, 200, membershipResponse{Team: team, Reassignments: reassignments})
+}
+
+type removeMembersRequest struct {
+	TeamName     string              `json:"team_name"`
+	UserIDs      []string            `json:"user_ids"`
+	ReviewPolicy domain.ReviewPolicy `json:"review_policy"`
+}
+
+// membershipResponse keeps the team fields at the top level
+type membershipResponse struct {
+	*domain.Team
+	Reassignments []domain.Reassignment `json:"reassignments"`
+}
+
+// POST /team/moveUser
+func (h *TeamHandler) MoveUser(w http.ResponseWriter, r *http.Request) {
+	req := moveUserRequest{ReviewPolicy: domain.ReviewsKeep}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	user, reassignments, err := h.service.MoveUser(
+		r.Context(),
+		req.UserID,
+		req.TeamName,
+		req.ReviewPolicy,
+	)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, 200, moveUserResponse{User: user, Reassignments: reassignments})
+}
+
+type moveUserRequest struct {
+	UserID       string              `json:"user_id"`
+	TeamName     string              `json:"team_name"`
+	ReviewPolicy domain.ReviewPolicy `json:"review_policy"`
+}
+
+type moveUserResponse struct {
+	domain.User
+	Reassignments []domain.Reassignment `json:"reassignments"`
+}
+
+// POST /team/delete
+func (h *TeamHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	req := deleteTeamRequest{ReviewPolicy: domain.ReviewsKeep}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	users, reassignments, err := h.service.DeleteTeam(
+		r.Context(),
+		req.TeamName,
+		req.Force,
+		req.ReviewPolicy,
+	)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	response := deleteTeamResponse{
+		TeamName:      req.TeamName,
+		Users:         users,
+		Reassignments: reassignments,
+	}
+
+	writeJSON(w, 200, response)
+}
+
+// deleteTeamRequest applies the review policy only when forced,
+// otherwise members have no open reviews
+type deleteTeamRequest struct {
+	TeamName     string              `json:"team_name"`
+	Force        bool                `json:"force"`
+	ReviewPolicy domain.ReviewPolicy `json:"review_policy"`
+}
+
+// deleteTeamResponse lists the members left without a team
+type deleteTeamResponse struct {
+	TeamName      string                `json:"team_name"`
+	Users         []domain.User         `json:"users"`
+	Reassignments []domain.Reassignment `json:"reassignments"`
+}
+
+// POST /team/rename
+func (h *TeamHandler) Rename(w http.ResponseWriter, r *http.Request) {
+	var req renameTeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	team, err := h.service.RenameTeam(r.Context(), req.TeamName, req.NewTeamName)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, 200, team)
+}
+
+type renameTeamRequest struct {
+	TeamName    string `json:"team_name"`
+	NewTeamName string `json:"new_team_name"`
+}
+
+// PUT /team
+func (h *TeamHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	req := syncTeamRequest{
+		Team:         domain.Team{TeamSettings: domain.DefaultTeamSettings()},
+		Unlisted:     domain.UnlistedDeactivate,
+		ReviewPolicy: domain.ReviewsKeep,
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	team, diff, err := h.service.SyncTeam(r.Context(), &req.Team, req.Unlisted, req.ReviewPolicy)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if diff.Created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, syncTeamResponse{Team: team, Diff: diff})
+}
+
+// syncTeamRequest is the whole desired team, settings
+// missing from it are reset to their defaults
+type syncTeamRequest struct {
+	domain.Team
+	Unlisted     domain.UnlistedPolicy `json:"unlisted_members"`
+	ReviewPolicy domain.ReviewPolicy   `json:"review_policy"`
+}
+
+type syncTeamResponse struct {
+	Team *domain.Team    `json:"team"`
+	Diff domain.TeamDiff `json:"diff"`
+}
+
+// POST /team/setParent
+func (h *TeamHandler) SetParent(w http.ResponseWriter, r *http.Request) {
+	var req setParentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	team, err := h.service.SetParent(r.Context(), req.TeamName, req.ParentTeam)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, 200, team)
+}
+
+// setParentRequest clears the parent when parent_team is empty
+type setParentRequest struct {
+	TeamName   string `json:"team_name"`
+	ParentTeam string `json:"parent_team"`
+}
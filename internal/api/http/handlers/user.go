@@ -33,13 +33,18 @@ func (h *UserHandler) SetIsActive(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.SetIsActive(r.Context(), req.UserID, req.IsActive)
+	user, reassignments, err := h.userService.SetIsActive(r.Context(), req.UserID, req.IsActive)
 	if err != nil {
 		sendError(w, err)
 		return
 	}
 
-	writeJSON(w, 200, user)
+	response := setActiveResponse{
+		User:          user,
+		Reassignments: reassignments,
+	}
+
+	writeJSON(w, 200, response)
 	return
 }
 
@@ -48,11 +53,64 @@ type setActiveRequest struct {
 	IsActive bool   `json:"is_active"`
 }
 
+// setActiveResponse keeps the user fields at the top level
+type setActiveResponse struct {
+	domain.User
+	Reassignments []domain.Reassignment `json:"reassignments"`
+}
+
+// POST /users/setMaxOpenReviews
+func (h *UserHandler) SetMaxOpenReviews(w http.ResponseWriter, r *http.Request) {
+	var req setMaxOpenReviewsRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	user, err := h.userService.SetMaxOpenReviews(r.Context(), req.UserID, req.MaxOpenReviews)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, 200, user)
+}
+
+type setMaxOpenReviewsRequest struct {
+	UserID         string `json:"user_id"`
+	MaxOpenReviews int    `json:"max_open_reviews"`
+}
+
+// POST /users/outOfOffice
+func (h *UserHandler) OutOfOffice(w http.ResponseWriter, r *http.Request) {
+	var req domain.OutOfOffice
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	ooo, err := h.userService.AddOutOfOffice(r.Context(), req)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, ooo)
+}
+
 func (h *UserHandler) GetReview(w http.ResponseWriter, r *http.Request) {
 	queryParams := r.URL.Query()
 
 	userID := queryParams.Get("user_id")
 
+	load, err := h.prService.GetReviewLoad(r.Context(), userID)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
 	prs, err := h.prService.GetPullRequestsForUser(r.Context(), userID)
 	if err != nil {
 		sendError(w, err)
@@ -60,8 +118,9 @@ func (h *UserHandler) GetReview(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := GetReviewResponse{
-		UserID: userID,
-		Prs:    prs,
+		UserID:     userID,
+		Prs:        prs,
+		ReviewLoad: load,
 	}
 
 	writeJSON(w, 200, response)
@@ -72,4 +131,59 @@ func (h *UserHandler) GetReview(w http.ResponseWriter, r *http.Request) {
 type GetReviewResponse struct {
 	UserID string               `json:"user_id"`
 	Prs    []domain.PullRequest `json:"pull_requests"`
+	domain.ReviewLoad
+}
+
+// GET /users/list
+func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := domain.UserFilter{
+		TeamName:       query.Get("team_name"),
+		UsernamePrefix: query.Get("name_prefix"),
+	}
+
+	var err error
+	if filter.IsActive, err = parseBool(query, "is_active"); err != nil {
+		sendError(w, err)
+		return
+	}
+	if filter.Limit, filter.After, err = parsePage(query); err != nil {
+		sendError(w, err)
+		return
+	}
+
+	page, err := h.userService.ListUsers(r.Context(), filter)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, 200, page)
+}
+
+// GET /users/get
+func (h *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+
+	user, err := h.userService.GetByID(r.Context(), userID)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	load, err := h.prService.GetReviewLoad(r.Context(), userID)
+	if err != nil {
+		sendError(w, err)
+		return
+	}
+
+	writeJSON(w, 200, getUserResponse{User: user, ReviewLoad: load})
+}
+
+// getUserResponse nests the load, its max_open_reviews is the
+// effective capacity while the user's own is only the override
+type getUserResponse struct {
+	domain.User
+	ReviewLoad domain.ReviewLoad `json:"review_load"`
 }
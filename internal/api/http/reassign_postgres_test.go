@@ -0,0 +1,48 @@
+//go:build postgres
+
+package httpserver_test
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	sqlrepo "github.com/ynsssss/pr-manager/internal/repository/sql"
+)
+
+// postgresRepositories connects to the migrated database at DATABASE_URL
+func postgresRepositories(t *testing.T) repositories {
+	t.Helper()
+
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		t.Fatal("DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		t.Fatalf("failed to open DB connection: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.PingContext(t.Context()); err != nil {
+		t.Fatalf("cannot connect to database: %v", err)
+	}
+
+	return repositories{
+		userRepo:  sqlrepo.NewUserRepository(db),
+		teamRepo:  sqlrepo.NewTeamRepository(db),
+		prRepo:    sqlrepo.NewPullRequestRepository(db),
+		txManager: sqlrepo.NewTxManager(db),
+		auditRepo: sqlrepo.NewAuditRepository(db),
+	}
+}
+
+func TestParallelReassignPostgres(t *testing.T) {
+	// the database isn't cleaned up, the prefix keeps runs apart
+	prefix := fmt.Sprintf("reassign-%d-", time.Now().UnixNano())
+	testParallelReassign(t, postgresRepositories(t), prefix)
+}
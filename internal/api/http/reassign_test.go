@@ -0,0 +1,321 @@
+package httpserver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	httpserver "github.com/ynsssss/pr-manager/internal/api/http"
+	"github.com/ynsssss/pr-manager/internal/api/http/handlers"
+	"github.com/ynsssss/pr-manager/internal/domain"
+	memoryrepo "github.com/ynsssss/pr-manager/internal/repository/memory"
+	"github.com/ynsssss/pr-manager/internal/service"
+)
+
+type repositories struct {
+	userRepo  service.UserRepository
+	teamRepo  service.TeamRepository
+	prRepo    service.PullRequestRepository
+	txManager service.TxManager
+	auditRepo service.AuditRepository
+}
+
+func memoryRepositories() repositories {
+	db := memoryrepo.NewDB()
+	return repositories{
+		userRepo:  memoryrepo.NewUserRepository(db),
+		teamRepo:  memoryrepo.NewTeamRepository(db),
+		prRepo:    memoryrepo.NewPullRequestRepository(db),
+		txManager: memoryrepo.NewTxManager(db),
+		auditRepo: memoryrepo.NewAuditRepository(db),
+	}
+}
+
+func newServer(t *testing.T, repos repositories) *httptest.Server {
+	t.Helper()
+
+	auditService := service.NewAuditService(repos.auditRepo)
+	prService := service.NewPullRequestService(
+		repos.prRepo, repos.userRepo, repos.teamRepo, repos.txManager,
+		service.NewRandomStrategy(), auditService,
+	)
+	userService := service.NewUserService(repos.userRepo, repos.txManager, prService, auditService)
+	teamService := service.NewTeamService(
+		repos.teamRepo, repos.userRepo, repos.prRepo, repos.txManager, prService, auditService,
+	)
+
+	server := httptest.NewServer(
+		httpserver.NewRouter(userService, teamService, prService, auditService, ""),
+	)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// post sends body as JSON and decodes the response into out on 2xx,
+// error responses are returned with the status
+func post(server *httptest.Server, path string, body, out any) (int, handlers.ErrorResponse, error) {
+	var errResp handlers.ErrorResponse
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return 0, errResp, err
+	}
+	resp, err := http.Post(server.URL+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return 0, errResp, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, errResp, json.NewDecoder(resp.Body).Decode(&errResp)
+	}
+	return resp.StatusCode, errResp, json.NewDecoder(resp.Body).Decode(out)
+}
+
+type reassignResponse struct {
+	PR         domain.PullRequest `json:"pr"`
+	ReplacedBy string             `json:"replaced_by"`
+}
+
+func reassign(server *httptest.Server, prID, oldReviewer string) (reassignResponse, int, handlers.ErrorResponse, error) {
+	var resp reassignResponse
+	status, errResp, err := post(server, "/pullRequest/reassign", map[string]string{
+		"pull_request_id": prID,
+		"old_reviewer_id": oldReviewer,
+	}, &resp)
+	return resp, status, errResp, err
+}
+
+type reassignment struct {
+	from, to string
+}
+
+// reassignTest drives one server, prefix keeps the IDs
+// of separate runs apart on shared storage
+type reassignTest struct {
+	server *httptest.Server
+	repos  repositories
+	prefix string
+}
+
+// addTeam creates a team of the author and seven active reviewers
+func (rt reassignTest) addTeam(t *testing.T) (author string) {
+	t.Helper()
+
+	author = rt.prefix + "author"
+	team := domain.Team{
+		Name:         rt.prefix + "backend",
+		TeamSettings: domain.DefaultTeamSettings(),
+		Members:      []domain.TeamMember{{UserID: author, Username: author, IsActive: true}},
+	}
+	for i := range 7 {
+		id := fmt.Sprintf("%su%d", rt.prefix, i)
+		team.Members = append(team.Members, domain.TeamMember{UserID: id, Username: id, IsActive: true})
+	}
+	status, errResp, err := post(rt.server, "/team/add", team, &domain.Team{})
+	if err != nil || status != http.StatusCreated {
+		t.Fatalf("add team: status %d, %+v, %v", status, errResp.Error, err)
+	}
+	return author
+}
+
+func (rt reassignTest) createPR(t *testing.T, id, author string) domain.PullRequest {
+	t.Helper()
+
+	var pr domain.PullRequest
+	status, errResp, err := post(rt.server, "/pullRequest/create", handlers.CreateRequest{
+		ID:       rt.prefix + id,
+		Name:     id,
+		AuthorID: author,
+	}, &pr)
+	if err != nil || status != http.StatusCreated {
+		t.Fatalf("create PR %q: status %d, %+v, %v", id, status, errResp.Error, err)
+	}
+	if len(pr.AssignedReviewers) != domain.DefaultMaxReviewers {
+		t.Fatalf("PR %q has reviewers %v, want %d", id, pr.AssignedReviewers, domain.DefaultMaxReviewers)
+	}
+	return pr
+}
+
+// testParallelReassign checks concurrent /pullRequest/reassign calls
+// against one storage, every call has to see the reviewers left by
+// the calls that committed before it
+func testParallelReassign(t *testing.T, repos repositories, prefix string) {
+	rt := reassignTest{server: newServer(t, repos), repos: repos, prefix: prefix}
+	author := rt.addTeam(t)
+
+	t.Run("both reviewers at once", func(t *testing.T) {
+		for i := range 20 {
+			rt.reassignBothReviewers(t, rt.createPR(t, fmt.Sprintf("pair-%d", i), author))
+		}
+	})
+	t.Run("random reviewers", func(t *testing.T) {
+		rt.reassignRandomReviewers(t, rt.createPR(t, "random", author))
+	})
+}
+
+// reassignBothReviewers replaces the two reviewers of the PR at the
+// same time, both calls have to win and the PR has to end up with
+// exactly their picks
+func (rt reassignTest) reassignBothReviewers(t *testing.T, pr domain.PullRequest) {
+	t.Helper()
+
+	var (
+		start   = make(chan struct{})
+		results = make([]reassignResponse, len(pr.AssignedReviewers))
+		wg      sync.WaitGroup
+	)
+	wg.Add(len(pr.AssignedReviewers))
+	for i, old := range pr.AssignedReviewers {
+		go func() {
+			defer wg.Done()
+			<-start
+
+			resp, status, errResp, err := reassign(rt.server, pr.ID, old)
+			if err != nil || status != http.StatusOK {
+				t.Errorf("reassign %q on %q: status %d, %+v, %v", old, pr.ID, status, errResp.Error, err)
+				return
+			}
+			results[i] = resp
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+
+	final, err := rt.repos.prRepo.GetByID(t.Context(), pr.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasDuplicates(final.AssignedReviewers) {
+		t.Fatalf("PR %q has duplicate reviewers: %v", pr.ID, final.AssignedReviewers)
+	}
+
+	// each replacement takes the place of the reviewer it replaced
+	winners := []string{results[0].ReplacedBy, results[1].ReplacedBy}
+	if !slices.Equal(final.AssignedReviewers, winners) {
+		t.Fatalf("PR %q has reviewers %v, want the replacements %v of %v",
+			pr.ID, final.AssignedReviewers, winners, pr.AssignedReviewers)
+	}
+	// one call may pick the reviewer the other one replaced,
+	// any other way back to the PR resurrects a replaced reviewer
+	for _, old := range pr.AssignedReviewers {
+		if slices.Contains(final.AssignedReviewers, old) && !slices.Contains(winners, old) {
+			t.Fatalf("replaced reviewer %q is assigned to %q again: %v", old, pr.ID, final.AssignedReviewers)
+		}
+	}
+}
+
+// reassignRandomReviewers keeps replacing whichever reviewer each
+// worker saw last, so calls often target reviewers that are gone
+func (rt reassignTest) reassignRandomReviewers(t *testing.T, pr domain.PullRequest) {
+	t.Helper()
+
+	const (
+		workers = 16
+		rounds  = 5
+	)
+
+	var (
+		mu        sync.Mutex
+		succeeded []reassignment
+		wg        sync.WaitGroup
+	)
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+
+			for range rounds {
+				current, err := rt.repos.prRepo.GetByID(t.Context(), pr.ID)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				old := current.AssignedReviewers[rand.IntN(len(current.AssignedReviewers))]
+
+				// the reviewer may be replaced by another worker before
+				// this request lands, that's answered with NOT_ASSIGNED
+				resp, status, errResp, err := reassign(rt.server, pr.ID, old)
+				switch {
+				case err != nil:
+					t.Error(err)
+					return
+				case status == http.StatusConflict && errResp.Error.Code == "NOT_ASSIGNED":
+					continue
+				case status != http.StatusOK:
+					t.Errorf("reassign %q: status %d, %+v", old, status, errResp.Error)
+					return
+				}
+
+				if hasDuplicates(resp.PR.AssignedReviewers) {
+					t.Errorf("response has duplicate reviewers: %v", resp.PR.AssignedReviewers)
+				}
+				if !slices.Contains(resp.PR.AssignedReviewers, resp.ReplacedBy) {
+					t.Errorf("replaced_by %q is not in the response's reviewers %v",
+						resp.ReplacedBy, resp.PR.AssignedReviewers)
+				}
+
+				mu.Lock()
+				succeeded = append(succeeded, reassignment{from: old, to: resp.ReplacedBy})
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+
+	final, err := rt.repos.prRepo.GetByID(t.Context(), pr.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasDuplicates(final.AssignedReviewers) {
+		t.Fatalf("PR ended up with duplicate reviewers: %v", final.AssignedReviewers)
+	}
+	if len(final.AssignedReviewers) != len(pr.AssignedReviewers) {
+		t.Fatalf("PR has reviewers %v, want %d", final.AssignedReviewers, len(pr.AssignedReviewers))
+	}
+	if len(succeeded) == 0 {
+		t.Fatal("no reassignment succeeded")
+	}
+
+	// every reviewer handed out is either still assigned or was
+	// replaced by a later call, so the successful calls have to add
+	// up to the difference between the initial and final reviewers
+	balance := make(map[string]int)
+	for _, id := range pr.AssignedReviewers {
+		balance[id]++
+	}
+	for _, r := range succeeded {
+		balance[r.to]++
+		balance[r.from]--
+	}
+	for id, n := range balance {
+		want := 0
+		if slices.Contains(final.AssignedReviewers, id) {
+			want = 1
+		}
+		if n != want {
+			t.Errorf("reviewer %q was assigned %d times more than removed, want %d", id, n, want)
+		}
+	}
+}
+
+func hasDuplicates(ids []string) bool {
+	sorted := slices.Sorted(slices.Values(ids))
+	return len(slices.Compact(sorted)) != len(ids)
+}
+
+func TestParallelReassignMemory(t *testing.T) {
+	testParallelReassign(t, memoryRepositories(), "")
+}
@@ -12,24 +12,51 @@ func NewRouter(
 	userService *service.UserService,
 	teamService *service.TeamService,
 	prService *service.PullRequestService,
+	auditService *service.AuditService,
+	adminToken string,
 ) *mux.Router {
 	router := mux.NewRouter()
+	router.Use(handlers.WithActor)
 
 	// Users
 	userHandler := handlers.NewUserHandler(userService, prService)
 	router.HandleFunc("/users/setIsActive", userHandler.SetIsActive).Methods(http.MethodPost)
 	router.HandleFunc("/users/getReview", userHandler.GetReview).Methods(http.MethodGet)
+	router.HandleFunc("/users/setMaxOpenReviews", userHandler.SetMaxOpenReviews).Methods(http.MethodPost)
+	router.HandleFunc("/users/outOfOffice", userHandler.OutOfOffice).Methods(http.MethodPost)
+	router.HandleFunc("/users/list", userHandler.List).Methods(http.MethodGet)
+	router.HandleFunc("/users/get", userHandler.Get).Methods(http.MethodGet)
 
 	// Teams
 	teamHandler := handlers.NewTeamHandler(teamService)
 	router.HandleFunc("/team/add", teamHandler.Add).Methods(http.MethodPost)
+	router.HandleFunc("/team", teamHandler.Sync).Methods(http.MethodPut)
 	router.HandleFunc("/team/get", teamHandler.GetByName).Methods(http.MethodGet)
+	router.HandleFunc("/team/list", teamHandler.List).Methods(http.MethodGet)
+	router.HandleFunc("/team/settings", teamHandler.UpdateSettings).Methods(http.MethodPost)
+	router.HandleFunc("/team/deactivateUsers", teamHandler.DeactivateUsers).Methods(http.MethodPost)
+	router.HandleFunc("/team/addMembers", teamHandler.AddMembers).Methods(http.MethodPost)
+	router.HandleFunc("/team/removeMembers", teamHandler.RemoveMembers).Methods(http.MethodPost)
+	router.HandleFunc("/team/moveUser", teamHandler.MoveUser).Methods(http.MethodPost)
+	router.HandleFunc("/team/delete", teamHandler.Delete).Methods(http.MethodPost)
+	router.HandleFunc("/team/rename", teamHandler.Rename).Methods(http.MethodPost)
+	router.HandleFunc("/team/setParent", teamHandler.SetParent).Methods(http.MethodPost)
 
 	// Pull Requests
-	prHandler := handlers.NewPRHandler(prService)
+	prHandler := handlers.NewPRHandler(prService, adminToken)
 	router.HandleFunc("/pullRequest/create", prHandler.Create).Methods(http.MethodPost)
 	router.HandleFunc("/pullRequest/merge", prHandler.Merge).Methods(http.MethodPost)
 	router.HandleFunc("/pullRequest/reassign", prHandler.Reassign).Methods(http.MethodPost)
+	router.HandleFunc("/pullRequest/review", prHandler.Review).Methods(http.MethodPost)
+	router.HandleFunc("/pullRequest/close", prHandler.Close).Methods(http.MethodPost)
+	router.HandleFunc("/pullRequest/reopen", prHandler.Reopen).Methods(http.MethodPost)
+	router.HandleFunc("/pullRequest/ready", prHandler.Ready).Methods(http.MethodPost)
+	router.HandleFunc("/pullRequest/list", prHandler.List).Methods(http.MethodGet)
+	router.HandleFunc("/pullRequest/get", prHandler.Get).Methods(http.MethodGet)
+
+	// Audit
+	auditHandler := handlers.NewAuditHandler(auditService)
+	router.HandleFunc("/audit", auditHandler.List).Methods(http.MethodGet)
 
 	return router
 }
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntityType names the kind of entity an audit event is about
+type AuditEntityType string
+
+const (
+	AuditPullRequest AuditEntityType = "PULL_REQUEST"
+	AuditTeam        AuditEntityType = "TEAM"
+	AuditUser        AuditEntityType = "USER"
+)
+
+func (t AuditEntityType) Validate() error {
+	switch t {
+	case AuditPullRequest, AuditTeam, AuditUser:
+		return nil
+	}
+	return ErrInvalidAuditEntity
+}
+
+type AuditEventType string
+
+const (
+	EventPRCreated            AuditEventType = "PR_CREATED"
+	EventPRReviewerReassigned AuditEventType = "PR_REVIEWER_REASSIGNED"
+	EventPRReviewed           AuditEventType = "PR_REVIEWED"
+	EventPRMarkedReady        AuditEventType = "PR_MARKED_READY"
+	EventPRMerged             AuditEventType = "PR_MERGED"
+	EventPRClosed             AuditEventType = "PR_CLOSED"
+	EventPRReopened           AuditEventType = "PR_REOPENED"
+
+	EventTeamCreated          AuditEventType = "TEAM_CREATED"
+	EventTeamSynced           AuditEventType = "TEAM_SYNCED"
+	EventTeamSettingsUpdated  AuditEventType = "TEAM_SETTINGS_UPDATED"
+	EventTeamParentChanged    AuditEventType = "TEAM_PARENT_CHANGED"
+	EventTeamMembersAdded     AuditEventType = "TEAM_MEMBERS_ADDED"
+	EventTeamMembersRemoved   AuditEventType = "TEAM_MEMBERS_REMOVED"
+	EventTeamUsersDeactivated AuditEventType = "TEAM_USERS_DEACTIVATED"
+	EventTeamRenamed          AuditEventType = "TEAM_RENAMED"
+	EventTeamDeleted          AuditEventType = "TEAM_DELETED"
+
+	EventUserActiveChanged    AuditEventType = "USER_ACTIVE_CHANGED"
+	EventUserCapacityChanged  AuditEventType = "USER_CAPACITY_CHANGED"
+	EventUserTeamChanged      AuditEventType = "USER_TEAM_CHANGED"
+	EventUserOutOfOfficeAdded AuditEventType = "USER_OUT_OF_OFFICE_ADDED"
+)
+
+// SystemActor is the actor of changes made by background jobs
+const SystemActor = "system"
+
+// AuditEvent records a single change, Before is null for created
+// entities and After is null for deleted ones
+type AuditEvent struct {
+	ID         int64           `json:"event_id"`
+	EventType  AuditEventType  `json:"event_type"`
+	EntityType AuditEntityType `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	// Actor is empty when the request didn't name one
+	Actor     string          `json:"actor"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditFilter selects audit events for listing, zero fields
+// don't filter. The time range includes From and excludes To.
+type AuditFilter struct {
+	EntityType AuditEntityType
+	// EntityID requires EntityType, user IDs and team names may clash
+	EntityID string
+
+	From *time.Time
+	To   *time.Time
+
+	// After continues the listing, events are ordered
+	// from the newest to the oldest
+	After *Cursor
+	Limit int
+}
+
+func (f *AuditFilter) Validate() error {
+	if f.EntityType != "" {
+		if err := f.EntityType.Validate(); err != nil {
+			return err
+		}
+	}
+	if f.EntityID != "" && f.EntityType == "" {
+		return ErrAuditEntityIDWithoutType
+	}
+	if f.From != nil && f.To != nil && f.To.Before(*f.From) {
+		return ErrInvalidTimeRange
+	}
+	if f.After != nil {
+		if _, err := f.After.EventID(); err != nil {
+			return err
+		}
+	}
+	return validatePageLimit(f.Limit)
+}
+
+type AuditPage struct {
+	Events     []AuditEvent `json:"events"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
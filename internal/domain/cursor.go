@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+// Cursor is the keyset position after which the next page starts.
+// Clients only see it encoded, so its fields can change freely.
+// Pages carry it as next_cursor, which is empty on the last page.
+type Cursor struct {
+	Time time.Time `json:"t,omitzero"`
+	ID   string    `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// EventID reads the position of an audit listing cursor
+func (c Cursor) EventID() (int64, error) {
+	id, err := strconv.ParseInt(c.ID, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+func validatePageLimit(limit int) error {
+	if limit < 1 || limit > MaxPageLimit {
+		return ErrInvalidPageLimit
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package domain
 
-import "errors"
-
+import (
+	"errors"
+	"fmt"
+)
 
 // TODO: make custom errors
 var (
@@ -9,8 +11,49 @@ var (
 	ErrNotFound    = errors.New("resource not found")
 	ErrPRExists    = errors.New("PR already exists")
 	ErrPRMerged    = errors.New("PR is already merged")
+	ErrPRNotOpen   = errors.New("PR is not open")
 	ErrNotAssigned = errors.New("reviewer not assigned to this PR")
 	ErrNoCandidate = errors.New("no active replacement candidate in team")
+	ErrForbidden   = errors.New("operation is not allowed")
+
+	ErrInvalidTransition = errors.New("PR status transition is not allowed")
+
+	ErrMergeBlocked = errors.New("merge requirements are not met")
+
+	ErrReviewersAtCapacity = errors.New("not enough reviewers below their review capacity")
+	ErrTeamHasOpenPRs      = errors.New("team members have open pull requests")
+)
+
+// MergeBlockedError tells what a pull request is missing to be merged,
+// it matches ErrMergeBlocked with errors.Is
+type MergeBlockedError struct {
+	RequiredApprovals  int      `json:"required_approvals"`
+	Approvals          int      `json:"approvals"`
+	ChangesRequestedBy []string `json:"changes_requested_by"`
+}
+
+func (e *MergeBlockedError) Error() string {
+	return fmt.Sprintf(
+		"%s: %d of %d approvals, changes requested by %v",
+		ErrMergeBlocked, e.Approvals, e.RequiredApprovals, e.ChangesRequestedBy,
+	)
+}
+
+func (e *MergeBlockedError) Is(target error) bool {
+	return target == ErrMergeBlocked
+}
+
+// Listing specific domain errors
+var (
+	ErrInvalidCursor    = NewValidationError("cursor is invalid")
+	ErrInvalidPageLimit = NewValidationError("limit must be between 1 and 500")
+	ErrInvalidTimeRange = NewValidationError("time range ends before it starts")
+)
+
+// Audit specific domain errors
+var (
+	ErrInvalidAuditEntity       = NewValidationError("audit entity type is invalid")
+	ErrAuditEntityIDWithoutType = NewValidationError("entity_id requires entity_type")
 )
 
 // Team specific domain errors
@@ -18,12 +61,31 @@ var (
 	ErrEmptyTeamName       = NewValidationError("team name is empty")
 	ErrEmptyTeamMemberID   = NewValidationError("team member id is empty")
 	ErrEmptyTeamMemberName = NewValidationError("team member name is empty")
+
+	ErrNegativeMinReviewers = NewValidationError("min reviewers is negative")
+	ErrMaxReviewersBelowMin = NewValidationError("max reviewers is less than min reviewers")
+
+	ErrEmptyFallbackTeam     = NewValidationError("fallback team name is empty")
+	ErrDuplicateFallbackTeam = NewValidationError("fallback team is listed twice")
+	ErrSelfFallbackTeam      = NewValidationError("team cannot be its own fallback")
+	ErrTeamCycle             = NewValidationError("team cannot be its own ancestor")
+
+	ErrNegativeRequiredApprovals = NewValidationError("required approvals is negative")
+	ErrRequiredApprovalsAboveMax = NewValidationError("required approvals is greater than max reviewers")
+
+	ErrNegativeMaxOpenReviews = NewValidationError("max open reviews is negative")
+	ErrInvalidCapacityPolicy  = NewValidationError("capacity policy is invalid")
+	ErrInvalidReviewPolicy    = NewValidationError("review policy is invalid")
+	ErrInvalidUnlistedPolicy  = NewValidationError("unlisted members policy is invalid")
 )
 
 // User specific domain errors
 var (
 	ErrEmptyUserID   = NewValidationError("user ID is empty")
 	ErrEmptyUsername = NewValidationError("username is empty")
+	ErrNoUserIDs     = NewValidationError("user IDs are empty")
+
+	ErrOutOfOfficeEndBeforeStart = NewValidationError("out of office end is not after its start")
 )
 
 // Pull request specific domain errors
@@ -33,4 +95,7 @@ var (
 	ErrEmptyAuthorID    = NewValidationError("author ID is empty")
 	ErrInvalidStatus    = NewValidationError("pull request status is invalid")
 	ErrTooManyReviewers = NewValidationError("too many assigned reviewers")
+	ErrTooFewReviewers  = NewValidationError("too few assigned reviewers")
+	ErrInvalidVerdict   = NewValidationError("review verdict is invalid")
+	ErrEmptyForcedBy    = NewValidationError("forced merge requires forced_by")
 )
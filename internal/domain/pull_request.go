@@ -1,25 +1,162 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"slices"
+	"time"
+)
 
 type PRStatus string
 
 const (
+	// StatusDraft PRs have no reviewers until they are marked ready
+	StatusDraft  PRStatus = "DRAFT"
 	StatusOpen   PRStatus = "OPEN"
 	StatusMerged PRStatus = "MERGED"
+	// StatusClosed PRs are abandoned without merge and have no reviewers
+	StatusClosed PRStatus = "CLOSED"
 )
 
+// prTransitions lists the statuses each status can move to
+var prTransitions = map[PRStatus][]PRStatus{
+	StatusDraft:  {StatusOpen, StatusClosed},
+	StatusOpen:   {StatusMerged, StatusClosed},
+	StatusClosed: {StatusOpen},
+	StatusMerged: {},
+}
+
+func (s PRStatus) CanTransitionTo(next PRStatus) bool {
+	return slices.Contains(prTransitions[s], next)
+}
+
 type PullRequest struct {
-	ID                string    `json:"pull_request_id"`
-	Name              string    `json:"pull_request_name"`
-	AuthorID          string    `json:"author_id"`
-	Status            PRStatus  `json:"status"`
-	AssignedReviewers []string  `json:"assigned_reviewers"`
-	CreatedAt         time.Time `json:"createdAt"`
+	ID                string     `json:"pull_request_id"`
+	Name              string     `json:"pull_request_name"`
+	AuthorID          string     `json:"author_id"`
+	Status            PRStatus   `json:"status"`
+	AssignedReviewers []string   `json:"assigned_reviewers"`
+	CreatedAt         time.Time  `json:"createdAt"`
 	MergedAt          *time.Time `json:"mergedAt"`
+	Reviews           []Review   `json:"reviews"` // verdict history, oldest first
+	// MergeForcedBy is set when the merge policy was bypassed
+	MergeForcedBy string `json:"merge_forced_by,omitempty"`
+
+	// FallbackReviewers lists the reviewers assigned by the current
+	// operation that came from an ancestor or fallback team.
+	// It is not persisted.
+	FallbackReviewers []FallbackReviewer `json:"fallback_reviewers,omitempty"`
+}
+
+// CheckMergePolicy returns a *MergeBlockedError unless at least
+// requiredApprovals assigned reviewers approved the pull request
+// and none of them requested changes. The latest verdict of each
+// reviewer counts, comments don't override it.
+func (pr *PullRequest) CheckMergePolicy(requiredApprovals int) error {
+	verdicts := make(map[string]ReviewVerdict, len(pr.AssignedReviewers))
+	for _, review := range pr.Reviews {
+		if review.Verdict == VerdictCommented {
+			continue
+		}
+		verdicts[review.ReviewerID] = review.Verdict
+	}
+
+	blocked := MergeBlockedError{
+		RequiredApprovals:  requiredApprovals,
+		ChangesRequestedBy: []string{},
+	}
+	for _, reviewerID := range pr.AssignedReviewers {
+		switch verdicts[reviewerID] {
+		case VerdictApproved:
+			blocked.Approvals++
+		case VerdictChangesRequested:
+			blocked.ChangesRequestedBy = append(blocked.ChangesRequestedBy, reviewerID)
+		}
+	}
+
+	if blocked.Approvals < requiredApprovals || len(blocked.ChangesRequestedBy) > 0 {
+		return &blocked
+	}
+	return nil
+}
+
+// ReviewerDetails is an assigned reviewer with their latest verdict,
+// the verdict is empty until they review
+type ReviewerDetails struct {
+	UserID   string        `json:"user_id"`
+	Username string        `json:"username"`
+	Verdict  ReviewVerdict `json:"verdict,omitempty"`
+}
+
+// PullRequestDetails is a pull request with its reviewers resolved
+type PullRequestDetails struct {
+	PullRequest
+	Reviewers []ReviewerDetails `json:"reviewers"`
+}
+
+// NewPullRequestDetails resolves the reviewers of the pull request,
+// usernames maps user IDs to usernames
+func NewPullRequestDetails(pr PullRequest, usernames map[string]string) PullRequestDetails {
+	verdicts := make(map[string]ReviewVerdict, len(pr.AssignedReviewers))
+	for _, review := range pr.Reviews {
+		verdicts[review.ReviewerID] = review.Verdict
+	}
+
+	details := PullRequestDetails{
+		PullRequest: pr,
+		Reviewers:   make([]ReviewerDetails, 0, len(pr.AssignedReviewers)),
+	}
+	for _, reviewerID := range pr.AssignedReviewers {
+		details.Reviewers = append(details.Reviewers, ReviewerDetails{
+			UserID:   reviewerID,
+			Username: usernames[reviewerID],
+			Verdict:  verdicts[reviewerID],
+		})
+	}
+	return details
+}
+
+type ReviewVerdict string
+
+const (
+	VerdictApproved         ReviewVerdict = "APPROVED"
+	VerdictChangesRequested ReviewVerdict = "CHANGES_REQUESTED"
+	VerdictCommented        ReviewVerdict = "COMMENTED"
+)
+
+func (v ReviewVerdict) Validate() error {
+	switch v {
+	case VerdictApproved, VerdictChangesRequested, VerdictCommented:
+		return nil
+	default:
+		return ErrInvalidVerdict
+	}
+}
+
+type Review struct {
+	ReviewerID string        `json:"reviewer_id"`
+	Verdict    ReviewVerdict `json:"verdict"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// Reassignment reports what happened to one review assignment
+// when its reviewer had to be replaced
+type Reassignment struct {
+	PullRequestID string `json:"pull_request_id"`
+	OldReviewerID string `json:"old"`
+	NewReviewerID string `json:"new,omitempty"`
+	// NoCandidate is set when nobody could take over,
+	// the old reviewer stays assigned then
+	NoCandidate bool `json:"no_candidate,omitempty"`
 }
 
-func (pr *PullRequest) Validate() error {
+type FallbackReviewer struct {
+	UserID   string `json:"user_id"`
+	TeamName string `json:"team_name"`
+}
+
+// Validate checks the invariants of the PullRequest entity,
+// reviewer count limits come from the author's team settings
+func (pr *PullRequest) Validate(settings TeamSettings) error {
 	if pr.ID == "" {
 		return ErrEmptyID
 	}
@@ -29,13 +166,71 @@ func (pr *PullRequest) Validate() error {
 	if pr.AuthorID == "" {
 		return ErrEmptyAuthorID
 	}
-	switch pr.Status {
-	case StatusOpen, StatusMerged:
-	default:
+	if _, ok := prTransitions[pr.Status]; !ok {
 		return ErrInvalidStatus
 	}
-	if len(pr.AssignedReviewers) > 2 {
+	if len(pr.AssignedReviewers) > settings.MaxReviewers {
 		return ErrTooManyReviewers
 	}
+	if pr.Status == StatusOpen && len(pr.AssignedReviewers) < settings.MinReviewers {
+		return ErrTooFewReviewers
+	}
 	return nil
 }
+
+// TransitionTo moves the pull request to the next status.
+// Closing releases the reviewers, merging sets MergedAt.
+func (pr *PullRequest) TransitionTo(next PRStatus, now time.Time) error {
+	if !pr.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, pr.Status, next)
+	}
+
+	pr.Status = next
+	switch next {
+	case StatusMerged:
+		pr.MergedAt = &now
+	case StatusClosed:
+		pr.AssignedReviewers = []string{}
+	}
+	return nil
+}
+
+// PullRequestFilter selects pull requests for listing, zero fields
+// don't filter. Time ranges include From and exclude To.
+type PullRequestFilter struct {
+	Status     PRStatus
+	AuthorID   string
+	ReviewerID string
+	// TeamName matches the current team of the author
+	TeamName string
+
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	MergedFrom  *time.Time
+	MergedTo    *time.Time
+
+	// After continues the listing, pull requests are ordered
+	// from the newest to the oldest
+	After *Cursor
+	Limit int
+}
+
+func (f *PullRequestFilter) Validate() error {
+	if f.Status != "" {
+		if _, ok := prTransitions[f.Status]; !ok {
+			return ErrInvalidStatus
+		}
+	}
+	if f.CreatedFrom != nil && f.CreatedTo != nil && f.CreatedTo.Before(*f.CreatedFrom) {
+		return ErrInvalidTimeRange
+	}
+	if f.MergedFrom != nil && f.MergedTo != nil && f.MergedTo.Before(*f.MergedFrom) {
+		return ErrInvalidTimeRange
+	}
+	return validatePageLimit(f.Limit)
+}
+
+type PullRequestPage struct {
+	PullRequests []PullRequest `json:"pull_requests"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+}
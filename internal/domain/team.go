@@ -1,9 +1,15 @@
 package domain
 
+import "slices"
+
 type TeamMember struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 	IsActive bool   `json:"is_active"`
+	// MaxOpenReviews overrides the team default when positive
+	MaxOpenReviews int `json:"max_open_reviews"`
+	// TeamName is only set when members of sub-teams are listed
+	TeamName string `json:"team_name,omitempty"`
 }
 
 func (t *TeamMember) Validate() error {
@@ -13,19 +19,174 @@ func (t *TeamMember) Validate() error {
 	if t.Username == "" {
 		return ErrEmptyTeamMemberName
 	}
+	if t.MaxOpenReviews < 0 {
+		return ErrNegativeMaxOpenReviews
+	}
+
+	return nil
+}
+
+const (
+	DefaultMinReviewers      = 0
+	DefaultMaxReviewers      = 2
+	DefaultRequiredApprovals = 0
+)
+
+// CapacityPolicy decides what happens to a new pull request
+// when reviewers are skipped for being at capacity
+type CapacityPolicy string
+
+const (
+	CapacityAssignFewer CapacityPolicy = "ASSIGN_FEWER"
+	CapacityFail        CapacityPolicy = "FAIL"
+)
+
+// TeamSettings control how pull requests of the team's members
+// are reviewed
+type TeamSettings struct {
+	MinReviewers int `json:"min_reviewers"`
+	MaxReviewers int `json:"max_reviewers"`
+	// RequiredApprovals is the number of assigned reviewers
+	// that must approve a pull request before it is merged
+	RequiredApprovals int `json:"required_approvals"`
+	// FallbackTeams are asked in order for reviewers
+	// when the team itself cannot supply enough
+	FallbackTeams []string `json:"fallback_teams"`
+	// DefaultMaxOpenReviews limits the OPEN pull requests
+	// each member reviews at once, 0 means no limit
+	DefaultMaxOpenReviews int            `json:"default_max_open_reviews"`
+	CapacityPolicy        CapacityPolicy `json:"capacity_policy"`
+}
+
+func DefaultTeamSettings() TeamSettings {
+	return TeamSettings{
+		MinReviewers:      DefaultMinReviewers,
+		MaxReviewers:      DefaultMaxReviewers,
+		RequiredApprovals: DefaultRequiredApprovals,
+		FallbackTeams:     []string{},
+		CapacityPolicy:    CapacityAssignFewer,
+	}
+}
+
+func (s *TeamSettings) Equal(other TeamSettings) bool {
+	return s.MinReviewers == other.MinReviewers &&
+		s.MaxReviewers == other.MaxReviewers &&
+		s.RequiredApprovals == other.RequiredApprovals &&
+		slices.Equal(s.FallbackTeams, other.FallbackTeams) &&
+		s.DefaultMaxOpenReviews == other.DefaultMaxOpenReviews &&
+		s.CapacityPolicy == other.CapacityPolicy
+}
+
+func (s *TeamSettings) Validate() error {
+	if s.MinReviewers < 0 {
+		return ErrNegativeMinReviewers
+	}
+	if s.MaxReviewers < s.MinReviewers {
+		return ErrMaxReviewersBelowMin
+	}
+	if s.RequiredApprovals < 0 {
+		return ErrNegativeRequiredApprovals
+	}
+	if s.RequiredApprovals > s.MaxReviewers {
+		return ErrRequiredApprovalsAboveMax
+	}
+	if s.DefaultMaxOpenReviews < 0 {
+		return ErrNegativeMaxOpenReviews
+	}
+	if s.CapacityPolicy != CapacityAssignFewer && s.CapacityPolicy != CapacityFail {
+		return ErrInvalidCapacityPolicy
+	}
+	for i, name := range s.FallbackTeams {
+		if name == "" {
+			return ErrEmptyFallbackTeam
+		}
+		if slices.Contains(s.FallbackTeams[:i], name) {
+			return ErrDuplicateFallbackTeam
+		}
+	}
+	return nil
+}
+
+// ReviewPolicy decides what happens to the open reviews
+// of a user who leaves a team
+type ReviewPolicy string
+
+const (
+	ReviewsKeep     ReviewPolicy = "KEEP"
+	ReviewsReassign ReviewPolicy = "REASSIGN"
+)
 
+func (p ReviewPolicy) Validate() error {
+	if p != ReviewsKeep && p != ReviewsReassign {
+		return ErrInvalidReviewPolicy
+	}
 	return nil
 }
 
+// UnlistedPolicy decides what a team sync does to
+// the members missing from the payload
+type UnlistedPolicy string
+
+const (
+	UnlistedDeactivate UnlistedPolicy = "DEACTIVATE"
+	UnlistedRemove     UnlistedPolicy = "REMOVE"
+)
+
+func (p UnlistedPolicy) Validate() error {
+	if p != UnlistedDeactivate && p != UnlistedRemove {
+		return ErrInvalidUnlistedPolicy
+	}
+	return nil
+}
+
+// TeamDiff lists what a team sync changed, member lists hold user IDs
+type TeamDiff struct {
+	Created         bool           `json:"created"`
+	ParentChanged   bool           `json:"parent_changed"`
+	SettingsChanged bool           `json:"settings_changed"`
+	Added           []string       `json:"added"`
+	Updated         []string       `json:"updated"`
+	Deactivated     []string       `json:"deactivated"`
+	Removed         []string       `json:"removed"`
+	Reassignments   []Reassignment `json:"reassignments"`
+}
+
+// TeamStats summarizes a team for the team listing
+type TeamStats struct {
+	TeamName          string `json:"team_name"`
+	ParentTeam        string `json:"parent_team"`
+	MinReviewers      int    `json:"min_reviewers"`
+	MemberCount       int    `json:"member_count"`
+	ActiveMemberCount int    `json:"active_member_count"`
+	// OpenPRCount counts the OPEN pull requests authored by members
+	OpenPRCount int `json:"open_pr_count"`
+	// AvgReviewLoad is the mean number of OPEN pull requests
+	// reviewed by an active member, 0 for teams without one
+	AvgReviewLoad float64 `json:"avg_review_load"`
+}
+
 type Team struct {
 	Name    string       `json:"team_name"`
 	Members []TeamMember `json:"members"`
+	// ParentTeam is asked for reviewers before the fallback teams
+	// when the team cannot supply enough, empty for top-level teams
+	ParentTeam string `json:"parent_team"`
+	TeamSettings
 }
 
 func (t *Team) Validate() error {
 	if t.Name == "" {
 		return ErrEmptyTeamName
 	}
+	if err := t.TeamSettings.Validate(); err != nil {
+		return err
+	}
+	if slices.Contains(t.FallbackTeams, t.Name) {
+		return ErrSelfFallbackTeam
+	}
+	if t.ParentTeam == t.Name {
+		return ErrTeamCycle
+	}
 
 	for _, member := range t.Members {
 		if err := member.Validate(); err != nil {
@@ -35,3 +196,12 @@ func (t *Team) Validate() error {
 
 	return nil
 }
+
+// CapacityOf returns the maximum number of OPEN pull requests
+// the member may review at once, 0 means no limit
+func (t *Team) CapacityOf(member TeamMember) int {
+	if member.MaxOpenReviews > 0 {
+		return member.MaxOpenReviews
+	}
+	return t.DefaultMaxOpenReviews
+}
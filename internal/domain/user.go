@@ -1,10 +1,14 @@
 package domain
 
+import "time"
+
 type User struct {
 	ID       string `json:"user_id"`
 	Username string `json:"username"`
 	TeamName string `json:"team_name"`
 	IsActive bool   `json:"is_active"`
+	// MaxOpenReviews overrides the team default when positive
+	MaxOpenReviews int `json:"max_open_reviews"`
 }
 
 // Validate checks the invariants of the User entity
@@ -15,5 +19,55 @@ func (u *User) Validate() error {
 	if u.Username == "" {
 		return ErrEmptyUsername
 	}
+	if u.MaxOpenReviews < 0 {
+		return ErrNegativeMaxOpenReviews
+	}
 	return nil
 }
+
+// ReviewLoad compares the OPEN pull requests a user reviews
+// with their capacity, 0 capacity means no limit
+type ReviewLoad struct {
+	OpenReviews    int `json:"open_reviews"`
+	MaxOpenReviews int `json:"max_open_reviews"`
+}
+
+// OutOfOffice is a window when the user can't review,
+// Start is inclusive and End is exclusive
+type OutOfOffice struct {
+	ID     int64     `json:"id"`
+	UserID string    `json:"user_id"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+}
+
+func (o *OutOfOffice) Validate() error {
+	if o.UserID == "" {
+		return ErrEmptyUserID
+	}
+	if !o.End.After(o.Start) {
+		return ErrOutOfOfficeEndBeforeStart
+	}
+	return nil
+}
+
+// UserFilter selects users for listing, zero fields don't filter
+type UserFilter struct {
+	TeamName string
+	IsActive *bool
+	// UsernamePrefix matches case-insensitively
+	UsernamePrefix string
+
+	// After continues the listing, users are ordered by ID
+	After *Cursor
+	Limit int
+}
+
+func (f *UserFilter) Validate() error {
+	return validatePageLimit(f.Limit)
+}
+
+type UserPage struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
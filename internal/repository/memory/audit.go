@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/ynsssss/pr-manager/internal/domain"
+)
+
+type AuditRepository struct {
+	db *DB
+}
+
+func NewAuditRepository(db *DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) AddEvents(ctx context.Context, events []domain.AuditEvent) error {
+	return r.db.write(ctx, func() error {
+		now := time.Now()
+		for _, event := range events {
+			r.db.lastAuditEventID++
+			event.ID = r.db.lastAuditEventID
+			event.CreatedAt = now
+			r.db.auditEvents = append(r.db.auditEvents, event)
+		}
+		return nil
+	})
+}
+
+// ListEvents returns up to filter.Limit events ordered by ID, newest first
+func (r *AuditRepository) ListEvents(
+	ctx context.Context,
+	filter domain.AuditFilter,
+) ([]domain.AuditEvent, error) {
+	var afterID int64
+	if filter.After != nil {
+		var err error
+		if afterID, err = filter.After.EventID(); err != nil {
+			return nil, err
+		}
+	}
+
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	events := make([]domain.AuditEvent, 0, filter.Limit)
+	for i := len(r.db.auditEvents) - 1; i >= 0 && len(events) < filter.Limit; i-- {
+		event := r.db.auditEvents[i]
+		switch {
+		case filter.After != nil && event.ID >= afterID,
+			filter.EntityType != "" && event.EntityType != filter.EntityType,
+			filter.EntityID != "" && event.EntityID != filter.EntityID,
+			filter.From != nil && event.CreatedAt.Before(*filter.From),
+			filter.To != nil && !event.CreatedAt.Before(*filter.To):
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/ynsssss/pr-manager/internal/domain"
+)
+
+// DB is an in-memory replacement for the PostgreSQL database.
+// It is shared by the repositories of this package the same way
+// *sql.DB is shared by the sql repositories.
+type DB struct {
+	// writeMu serialises transactions, every mutation runs in one.
+	// Readers outside of a transaction share it, so they never
+	// observe changes that may still be rolled back.
+	writeMu sync.RWMutex
+	mu      sync.RWMutex
+
+	tables
+}
+
+type tables struct {
+	teams        map[string]domain.Team // members are kept in users
+	users        map[string]domain.User
+	pullRequests map[string]domain.PullRequest
+	rotations    map[string]string
+	outOfOffice  map[int64]outOfOfficeRow
+	// auditEvents is append-only and ordered by ID
+	auditEvents []domain.AuditEvent
+
+	lastOutOfOfficeID int64
+	lastAuditEventID  int64
+}
+
+type outOfOfficeRow struct {
+	domain.OutOfOffice
+	reassignedAt *time.Time
+}
+
+func (row outOfOfficeRow) covers(at time.Time) bool {
+	return !at.Before(row.Start) && at.Before(row.End)
+}
+
+func NewDB() *DB {
+	return &DB{
+		tables: tables{
+			teams:        make(map[string]domain.Team),
+			users:        make(map[string]domain.User),
+			pullRequests: make(map[string]domain.PullRequest),
+			rotations:    make(map[string]string),
+			outOfOffice:  make(map[int64]outOfOfficeRow),
+		},
+	}
+}
+
+type txKey struct{}
+
+// inTx runs fn in the ambient transaction or, if there is none,
+// in a new one. The data is restored from a snapshot when fn fails.
+// Transactions are serialised and hide their changes from readers
+// outside of them until they are done.
+func (db *DB) inTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if db.inAmbientTx(ctx) {
+		return fn(ctx)
+	}
+
+	db.writeMu.Lock()
+	defer db.writeMu.Unlock()
+
+	db.mu.RLock()
+	snapshot := db.tables.clone()
+	db.mu.RUnlock()
+
+	defer func() {
+		p := recover()
+		if err != nil || p != nil {
+			db.mu.Lock()
+			db.tables = snapshot
+			db.mu.Unlock()
+		}
+		if p != nil {
+			panic(p)
+		}
+	}()
+
+	return fn(context.WithValue(ctx, txKey{}, db))
+}
+
+func (db *DB) inAmbientTx(ctx context.Context) bool {
+	owner, ok := ctx.Value(txKey{}).(*DB)
+	return ok && owner == db
+}
+
+// rlock takes the data lock for reading. Outside of a transaction it
+// first waits for the running one to finish, as in READ COMMITTED.
+func (db *DB) rlock(ctx context.Context) (unlock func()) {
+	if db.inAmbientTx(ctx) {
+		db.mu.RLock()
+		return db.mu.RUnlock
+	}
+
+	db.writeMu.RLock()
+	db.mu.RLock()
+	return func() {
+		db.mu.RUnlock()
+		db.writeMu.RUnlock()
+	}
+}
+
+// write runs fn in a transaction holding the data lock
+func (db *DB) write(ctx context.Context, fn func() error) error {
+	return db.inTx(ctx, func(ctx context.Context) error {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+
+		return fn()
+	})
+}
+
+func (t tables) clone() tables {
+	return tables{
+		teams:        maps.Clone(t.teams),
+		users:        maps.Clone(t.users),
+		pullRequests: maps.Clone(t.pullRequests),
+		rotations:    maps.Clone(t.rotations),
+		outOfOffice:  maps.Clone(t.outOfOffice),
+		// a snapshot keeps its own length, so events appended
+		// after it was taken never show up in it
+		auditEvents: t.auditEvents,
+
+		lastOutOfOfficeID: t.lastOutOfOfficeID,
+		lastAuditEventID:  t.lastAuditEventID,
+	}
+}
+
+func clonePullRequest(pr domain.PullRequest) domain.PullRequest {
+	pr.AssignedReviewers = append([]string{}, pr.AssignedReviewers...)
+	pr.Reviews = append([]domain.Review{}, pr.Reviews...)
+	if pr.MergedAt != nil {
+		mergedAt := *pr.MergedAt
+		pr.MergedAt = &mergedAt
+	}
+	return pr
+}
+
+func cloneSettings(settings domain.TeamSettings) domain.TeamSettings {
+	settings.FallbackTeams = append([]string{}, settings.FallbackTeams...)
+	return settings
+}
+
+type TxManager struct {
+	db *DB
+}
+
+func NewTxManager(db *DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.db.inTx(ctx, fn)
+}
@@ -0,0 +1,284 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ynsssss/pr-manager/internal/domain"
+)
+
+type PullRequestRepository struct {
+	db *DB
+}
+
+func NewPullRequestRepository(db *DB) *PullRequestRepository {
+	return &PullRequestRepository{db: db}
+}
+
+func (r *PullRequestRepository) Create(
+	ctx context.Context,
+	newPR *domain.PullRequest,
+) (*domain.PullRequest, error) {
+	newPR.CreatedAt = time.Now()
+
+	var pr domain.PullRequest
+	err := r.db.write(ctx, func() error {
+		if _, ok := r.db.pullRequests[newPR.ID]; ok {
+			return domain.ErrPRExists
+		}
+		if _, ok := r.db.users[newPR.AuthorID]; !ok {
+			return domain.ErrNotFound
+		}
+		pr = clonePullRequest(*newPR)
+		pr.Reviews = []domain.Review{}
+		pr.FallbackReviewers = nil
+		r.db.pullRequests[pr.ID] = pr
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pr = clonePullRequest(pr)
+	return &pr, nil
+}
+
+func (r *PullRequestRepository) GetByID(
+	ctx context.Context,
+	prID string,
+) (*domain.PullRequest, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	pr, ok := r.db.pullRequests[prID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	pr = clonePullRequest(pr)
+	return &pr, nil
+}
+
+// UpdateWithFn is used to query a pr and update it's value
+// in a single transaction based on the business logic
+// provided with closure function. It joins the ambient
+// transaction if the context carries one.
+func (r *PullRequestRepository) UpdateWithFn(
+	ctx context.Context,
+	id string,
+	updateFn func(pr *domain.PullRequest) (*domain.PullRequest, error),
+) (*domain.PullRequest, error) {
+	var pr *domain.PullRequest
+	err := r.db.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		pr, err = r.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		pr, err = updateFn(pr)
+		if err != nil {
+			return err
+		}
+
+		stored := clonePullRequest(*pr)
+
+		r.db.mu.Lock()
+		defer r.db.mu.Unlock()
+
+		// Only the mutable columns are written back, as in the sql implementation
+		current := r.db.pullRequests[stored.ID]
+		current.Name = stored.Name
+		current.Status = stored.Status
+		current.AssignedReviewers = stored.AssignedReviewers
+		current.MergedAt = stored.MergedAt
+		current.Reviews = stored.Reviews
+		current.MergeForcedBy = stored.MergeForcedBy
+		r.db.pullRequests[stored.ID] = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pr, nil
+}
+
+func (r *PullRequestRepository) GetPullRequestsForUser(
+	ctx context.Context,
+	userID string,
+) ([]domain.PullRequest, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	var prs []domain.PullRequest
+
+	for _, pr := range r.db.pullRequests {
+		if slices.Contains(pr.AssignedReviewers, userID) {
+			prs = append(prs, clonePullRequest(pr))
+		}
+	}
+	slices.SortFunc(prs, func(a, b domain.PullRequest) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return prs, nil
+}
+
+// ListPullRequests returns up to filter.Limit pull requests
+// ordered by creation time and ID, newest first
+func (r *PullRequestRepository) ListPullRequests(
+	ctx context.Context,
+	filter domain.PullRequestFilter,
+) ([]domain.PullRequest, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	prs := make([]domain.PullRequest, 0)
+	for _, pr := range r.db.pullRequests {
+		if r.matches(pr, filter) {
+			prs = append(prs, pr)
+		}
+	}
+	slices.SortFunc(prs, func(a, b domain.PullRequest) int {
+		return -compareKeyset(a, b.CreatedAt, b.ID)
+	})
+
+	prs = prs[:min(len(prs), filter.Limit)]
+	for i, pr := range prs {
+		prs[i] = clonePullRequest(pr)
+	}
+	return prs, nil
+}
+
+// matches expects the caller to hold the data lock
+func (r *PullRequestRepository) matches(pr domain.PullRequest, filter domain.PullRequestFilter) bool {
+	inRange := func(t *time.Time, from, to *time.Time) bool {
+		if from == nil && to == nil {
+			return true
+		}
+		if t == nil {
+			return false
+		}
+		return (from == nil || !t.Before(*from)) && (to == nil || t.Before(*to))
+	}
+
+	switch {
+	case filter.Status != "" && pr.Status != filter.Status,
+		filter.AuthorID != "" && pr.AuthorID != filter.AuthorID,
+		filter.ReviewerID != "" && !slices.Contains(pr.AssignedReviewers, filter.ReviewerID),
+		filter.TeamName != "" && r.db.users[pr.AuthorID].TeamName != filter.TeamName,
+		!inRange(&pr.CreatedAt, filter.CreatedFrom, filter.CreatedTo),
+		!inRange(pr.MergedAt, filter.MergedFrom, filter.MergedTo),
+		filter.After != nil && compareKeyset(pr, filter.After.Time, filter.After.ID) >= 0:
+		return false
+	}
+	return true
+}
+
+// compareKeyset compares the (created_at, id) key of the pull request
+func compareKeyset(pr domain.PullRequest, createdAt time.Time, id string) int {
+	if c := pr.CreatedAt.Compare(createdAt); c != 0 {
+		return c
+	}
+	return strings.Compare(pr.ID, id)
+}
+
+func (r *PullRequestRepository) GetOpenPullRequestsForReviewers(
+	ctx context.Context,
+	userIDs []string,
+) ([]domain.PullRequest, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	prs := make([]domain.PullRequest, 0)
+	for _, pr := range r.db.pullRequests {
+		if pr.Status != domain.StatusOpen {
+			continue
+		}
+		if slices.ContainsFunc(pr.AssignedReviewers, func(id string) bool {
+			return slices.Contains(userIDs, id)
+		}) {
+			prs = append(prs, clonePullRequest(pr))
+		}
+	}
+	slices.SortFunc(prs, func(a, b domain.PullRequest) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return prs, nil
+}
+
+func (r *PullRequestRepository) ReplaceReviewers(
+	ctx context.Context,
+	reassignments []domain.Reassignment,
+) error {
+	return r.db.write(ctx, func() error {
+		for _, reassignment := range reassignments {
+			if reassignment.NewReviewerID == "" {
+				continue
+			}
+			pr, ok := r.db.pullRequests[reassignment.PullRequestID]
+			if !ok {
+				continue
+			}
+
+			pr = clonePullRequest(pr)
+			for i, id := range pr.AssignedReviewers {
+				if id == reassignment.OldReviewerID {
+					pr.AssignedReviewers[i] = reassignment.NewReviewerID
+				}
+			}
+			r.db.pullRequests[pr.ID] = pr
+		}
+		return nil
+	})
+}
+
+func (r *PullRequestRepository) CountOpenReviews(
+	ctx context.Context,
+	userIDs []string,
+) (map[string]int, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	load := make(map[string]int, len(userIDs))
+	for _, pr := range r.db.pullRequests {
+		if pr.Status != domain.StatusOpen {
+			continue
+		}
+		for _, reviewerID := range pr.AssignedReviewers {
+			if slices.Contains(userIDs, reviewerID) {
+				load[reviewerID]++
+			}
+		}
+	}
+
+	return load, nil
+}
+
+func (r *PullRequestRepository) HasOpenPullRequests(
+	ctx context.Context,
+	userIDs []string,
+) (bool, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	for _, pr := range r.db.pullRequests {
+		switch pr.Status {
+		case domain.StatusOpen:
+			if slices.Contains(userIDs, pr.AuthorID) ||
+				slices.ContainsFunc(pr.AssignedReviewers, func(id string) bool {
+					return slices.Contains(userIDs, id)
+				}) {
+				return true, nil
+			}
+		case domain.StatusDraft:
+			if slices.Contains(userIDs, pr.AuthorID) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
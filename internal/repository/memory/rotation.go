@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/ynsssss/pr-manager/internal/domain"
+)
+
+type RotationRepository struct {
+	db *DB
+}
+
+func NewRotationRepository(db *DB) *RotationRepository {
+	return &RotationRepository{db: db}
+}
+
+func (r *RotationRepository) UpdateCursorWithFn(
+	ctx context.Context,
+	teamName string,
+	updateFn func(lastUserID string) (string, error),
+) error {
+	return r.db.inTx(ctx, func(ctx context.Context) error {
+		r.db.mu.RLock()
+		_, ok := r.db.teams[teamName]
+		lastUserID := r.db.rotations[teamName]
+		r.db.mu.RUnlock()
+		if !ok {
+			return domain.ErrNotFound
+		}
+
+		lastUserID, err := updateFn(lastUserID)
+		if err != nil {
+			return err
+		}
+
+		r.db.mu.Lock()
+		r.db.rotations[teamName] = lastUserID
+		r.db.mu.Unlock()
+		return nil
+	})
+}
@@ -0,0 +1,351 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ynsssss/pr-manager/internal/domain"
+)
+
+type TeamRepository struct {
+	db *DB
+}
+
+func NewTeamRepository(db *DB) *TeamRepository {
+	return &TeamRepository{db: db}
+}
+
+func (r *TeamRepository) TeamExists(ctx context.Context, name string) (bool, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	_, ok := r.db.teams[name]
+	return ok, nil
+}
+
+func (r *TeamRepository) CreateTeam(
+	ctx context.Context,
+	team *domain.Team,
+) (*domain.Team, error) {
+	err := r.db.write(ctx, func() error {
+		if _, ok := r.db.teams[team.Name]; ok {
+			return domain.ErrTeamExists
+		}
+		if err := r.checkFallbacks(team.FallbackTeams); err != nil {
+			return err
+		}
+		if _, ok := r.db.teams[team.ParentTeam]; team.ParentTeam != "" && !ok {
+			return fmt.Errorf("parent team %q: %w", team.ParentTeam, domain.ErrNotFound)
+		}
+		r.db.teams[team.Name] = domain.Team{
+			Name:         team.Name,
+			ParentTeam:   team.ParentTeam,
+			TeamSettings: cloneSettings(team.TeamSettings),
+		}
+		return nil
+	})
+	return team, err
+}
+
+func (r *TeamRepository) GetTeamByName(
+	ctx context.Context,
+	teamName string,
+) (*domain.Team, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	return r.getTeamByName(teamName)
+}
+
+// TODO: move to userRepo and rename to GetUserTeam
+func (r *TeamRepository) GetTeamWithUser(
+	ctx context.Context,
+	userID string,
+) (*domain.Team, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	u, ok := r.db.users[userID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return r.getTeamByName(u.TeamName)
+}
+
+// GetTeamWithSubteams returns the team with the members of all
+// its sub-teams, every member carries the name of their own team
+func (r *TeamRepository) GetTeamWithSubteams(
+	ctx context.Context,
+	teamName string,
+) (*domain.Team, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	team, err := r.getTeamByName(teamName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range team.Members {
+		team.Members[i].TeamName = teamName
+	}
+
+	var subteams []string
+	for frontier := []string{teamName}; len(frontier) > 0; {
+		var next []string
+		for name, t := range r.db.teams {
+			if name == teamName || slices.Contains(subteams, name) {
+				continue
+			}
+			if slices.Contains(frontier, t.ParentTeam) {
+				next = append(next, name)
+			}
+		}
+		subteams = append(subteams, next...)
+		frontier = next
+	}
+	slices.Sort(subteams)
+
+	for _, name := range subteams {
+		subteam, err := r.getTeamByName(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range subteam.Members {
+			member.TeamName = name
+			team.Members = append(team.Members, member)
+		}
+	}
+	return team, nil
+}
+
+// ListTeamStats mirrors the aggregate query of the sql repository
+func (r *TeamRepository) ListTeamStats(ctx context.Context) ([]domain.TeamStats, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	load := make(map[string]int)
+	openPRs := make(map[string]int)
+	for _, pr := range r.db.pullRequests {
+		if pr.Status != domain.StatusOpen {
+			continue
+		}
+		for _, reviewerID := range pr.AssignedReviewers {
+			load[reviewerID]++
+		}
+		if author, ok := r.db.users[pr.AuthorID]; ok && author.TeamName != "" {
+			openPRs[author.TeamName]++
+		}
+	}
+
+	stats := make(map[string]*domain.TeamStats, len(r.db.teams))
+	reviews := make(map[string]int, len(r.db.teams))
+	for name, team := range r.db.teams {
+		stats[name] = &domain.TeamStats{
+			TeamName:     name,
+			ParentTeam:   team.ParentTeam,
+			MinReviewers: team.MinReviewers,
+			OpenPRCount:  openPRs[name],
+		}
+	}
+	for _, u := range r.db.users {
+		s, ok := stats[u.TeamName]
+		if !ok {
+			continue
+		}
+		s.MemberCount++
+		if u.IsActive {
+			s.ActiveMemberCount++
+			reviews[u.TeamName] += load[u.ID]
+		}
+	}
+
+	result := make([]domain.TeamStats, 0, len(stats))
+	for name, s := range stats {
+		if s.ActiveMemberCount > 0 {
+			s.AvgReviewLoad = float64(reviews[name]) / float64(s.ActiveMemberCount)
+		}
+		result = append(result, *s)
+	}
+	slices.SortFunc(result, func(a, b domain.TeamStats) int {
+		return strings.Compare(a.TeamName, b.TeamName)
+	})
+
+	return result, nil
+}
+
+func (r *TeamRepository) SetParentTeam(ctx context.Context, teamName, parentTeam string) error {
+	return r.db.write(ctx, func() error {
+		team, ok := r.db.teams[teamName]
+		if !ok {
+			return domain.ErrNotFound
+		}
+		if _, ok := r.db.teams[parentTeam]; parentTeam != "" && !ok {
+			return fmt.Errorf("parent team %q: %w", parentTeam, domain.ErrNotFound)
+		}
+		team.ParentTeam = parentTeam
+		r.db.teams[teamName] = team
+		return nil
+	})
+}
+
+// LockAncestors only reads, transactions that write
+// are already run one at a time
+func (r *TeamRepository) LockAncestors(ctx context.Context, teamName string) ([]string, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	var ancestors []string
+	for name := teamName; name != ""; {
+		if slices.Contains(ancestors, name) {
+			return nil, domain.ErrTeamCycle
+		}
+
+		team, ok := r.db.teams[name]
+		if !ok {
+			return nil, domain.ErrNotFound
+		}
+		ancestors = append(ancestors, name)
+		name = team.ParentTeam
+	}
+	return ancestors, nil
+}
+
+// getTeamByName expects the caller to hold the data lock
+func (r *TeamRepository) getTeamByName(teamName string) (*domain.Team, error) {
+	team, ok := r.db.teams[teamName]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+
+	members := make([]domain.TeamMember, 0)
+	for _, u := range r.db.users {
+		if u.TeamName != teamName {
+			continue
+		}
+		members = append(members, domain.TeamMember{
+			UserID:         u.ID,
+			Username:       u.Username,
+			IsActive:       u.IsActive,
+			MaxOpenReviews: u.MaxOpenReviews,
+		})
+	}
+	slices.SortFunc(members, func(a, b domain.TeamMember) int {
+		return strings.Compare(a.UserID, b.UserID)
+	})
+
+	team.Members = members
+	team.TeamSettings = cloneSettings(team.TeamSettings)
+	return &team, nil
+}
+
+// checkFallbacks mirrors the foreign keys of team_fallbacks,
+// the caller must hold the data lock
+func (r *TeamRepository) checkFallbacks(fallbacks []string) error {
+	for _, name := range fallbacks {
+		if _, ok := r.db.teams[name]; !ok {
+			return fmt.Errorf("fallback team %q: %w", name, domain.ErrNotFound)
+		}
+	}
+	return nil
+}
+
+func (r *TeamRepository) UpdateSettingsWithFn(
+	ctx context.Context,
+	teamName string,
+	updateFn func(settings *domain.TeamSettings) error,
+) error {
+	return r.db.inTx(ctx, func(ctx context.Context) error {
+		r.db.mu.RLock()
+		team, ok := r.db.teams[teamName]
+		r.db.mu.RUnlock()
+		if !ok {
+			return domain.ErrNotFound
+		}
+
+		team.TeamSettings = cloneSettings(team.TeamSettings)
+		if err := updateFn(&team.TeamSettings); err != nil {
+			return err
+		}
+		team.TeamSettings = cloneSettings(team.TeamSettings)
+
+		r.db.mu.Lock()
+		defer r.db.mu.Unlock()
+
+		if err := r.checkFallbacks(team.FallbackTeams); err != nil {
+			return err
+		}
+		r.db.teams[teamName] = team
+		return nil
+	})
+}
+
+// DeleteTeam mirrors the ON DELETE rules of the sql schema
+func (r *TeamRepository) DeleteTeam(ctx context.Context, teamName string) error {
+	return r.db.write(ctx, func() error {
+		if _, ok := r.db.teams[teamName]; !ok {
+			return domain.ErrNotFound
+		}
+
+		delete(r.db.teams, teamName)
+		delete(r.db.rotations, teamName)
+		r.replaceTeamReferences(teamName, "")
+		return nil
+	})
+}
+
+// RenameTeam mirrors the ON UPDATE CASCADE rules of the sql schema
+func (r *TeamRepository) RenameTeam(ctx context.Context, oldName, newName string) error {
+	return r.db.write(ctx, func() error {
+		team, ok := r.db.teams[oldName]
+		if !ok {
+			return domain.ErrNotFound
+		}
+		if _, ok := r.db.teams[newName]; ok {
+			return domain.ErrTeamExists
+		}
+
+		delete(r.db.teams, oldName)
+		team.Name = newName
+		r.db.teams[newName] = team
+
+		if cursor, ok := r.db.rotations[oldName]; ok {
+			delete(r.db.rotations, oldName)
+			r.db.rotations[newName] = cursor
+		}
+		r.replaceTeamReferences(oldName, newName)
+		return nil
+	})
+}
+
+// replaceTeamReferences points members, sub-teams and fallbacks
+// of oldName to newName, an empty newName detaches members and
+// sub-teams and drops the fallbacks. The caller must hold the data lock.
+func (r *TeamRepository) replaceTeamReferences(oldName, newName string) {
+	for id, u := range r.db.users {
+		if u.TeamName == oldName {
+			u.TeamName = newName
+			r.db.users[id] = u
+		}
+	}
+
+	for name, team := range r.db.teams {
+		if team.ParentTeam == oldName {
+			team.ParentTeam = newName
+			r.db.teams[name] = team
+		}
+
+		i := slices.Index(team.FallbackTeams, oldName)
+		if i < 0 {
+			continue
+		}
+		team.TeamSettings = cloneSettings(team.TeamSettings)
+		if newName == "" {
+			team.FallbackTeams = slices.Delete(team.FallbackTeams, i, i+1)
+		} else {
+			team.FallbackTeams[i] = newName
+		}
+		r.db.teams[name] = team
+	}
+}
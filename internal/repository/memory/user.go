@@ -0,0 +1,260 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ynsssss/pr-manager/internal/domain"
+)
+
+type UserRepository struct {
+	db *DB
+}
+
+func NewUserRepository(db *DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, userID string) (domain.User, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	u, ok := r.db.users[userID]
+	if !ok {
+		return domain.User{}, domain.ErrNotFound
+	}
+	return u, nil
+}
+
+func (r *UserRepository) GetByIDs(ctx context.Context, userIDs []string) ([]domain.User, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	ids := slices.Clone(userIDs)
+	slices.Sort(ids)
+
+	users := make([]domain.User, 0, len(ids))
+	for _, id := range slices.Compact(ids) {
+		if u, ok := r.db.users[id]; ok {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+// ListUsers returns up to filter.Limit users ordered by ID
+func (r *UserRepository) ListUsers(ctx context.Context, filter domain.UserFilter) ([]domain.User, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	prefix := strings.ToLower(filter.UsernamePrefix)
+	users := make([]domain.User, 0)
+	for _, u := range r.db.users {
+		switch {
+		case filter.TeamName != "" && u.TeamName != filter.TeamName,
+			filter.IsActive != nil && u.IsActive != *filter.IsActive,
+			!strings.HasPrefix(strings.ToLower(u.Username), prefix),
+			filter.After != nil && u.ID <= filter.After.ID:
+			continue
+		}
+		users = append(users, u)
+	}
+	slices.SortFunc(users, func(a, b domain.User) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return users[:min(len(users), filter.Limit)], nil
+}
+
+func (r *UserRepository) SetIsActive(
+	ctx context.Context,
+	userID string,
+	isActive bool,
+) (domain.User, error) {
+	var u domain.User
+	err := r.db.write(ctx, func() error {
+		var ok bool
+		u, ok = r.db.users[userID]
+		if !ok {
+			return domain.ErrNotFound
+		}
+		u.IsActive = isActive
+		r.db.users[userID] = u
+		return nil
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+	return u, nil
+}
+
+// SetTeamForUsers moves the users to the team, an empty
+// team name detaches them from their team
+func (r *UserRepository) SetTeamForUsers(
+	ctx context.Context,
+	userIDs []string,
+	teamName string,
+) ([]domain.User, error) {
+	users := make([]domain.User, 0, len(userIDs))
+	err := r.db.write(ctx, func() error {
+		if _, ok := r.db.teams[teamName]; teamName != "" && !ok {
+			return fmt.Errorf("team %q: %w", teamName, domain.ErrNotFound)
+		}
+		for _, id := range userIDs {
+			u, ok := r.db.users[id]
+			if !ok {
+				continue
+			}
+			u.TeamName = teamName
+			r.db.users[id] = u
+			users = append(users, u)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *UserRepository) SetMaxOpenReviews(
+	ctx context.Context,
+	userID string,
+	maxOpenReviews int,
+) (domain.User, error) {
+	var u domain.User
+	err := r.db.write(ctx, func() error {
+		var ok bool
+		u, ok = r.db.users[userID]
+		if !ok {
+			return domain.ErrNotFound
+		}
+		u.MaxOpenReviews = maxOpenReviews
+		r.db.users[userID] = u
+		return nil
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+	return u, nil
+}
+
+// UpsertUsers inserts the users or updates the existing ones.
+// Like the sql implementation it applies either all of them or none.
+func (r *UserRepository) UpsertUsers(ctx context.Context, users []domain.User) error {
+	return r.db.write(ctx, func() error {
+		for _, u := range users {
+			if _, ok := r.db.teams[u.TeamName]; !ok {
+				return fmt.Errorf("team %q of user %q: %w", u.TeamName, u.ID, domain.ErrNotFound)
+			}
+		}
+		for _, u := range users {
+			r.db.users[u.ID] = u
+		}
+		return nil
+	})
+}
+
+func (r *UserRepository) SetIsActiveForUsers(
+	ctx context.Context,
+	userIDs []string,
+	isActive bool,
+) ([]domain.User, error) {
+	users := make([]domain.User, 0, len(userIDs))
+	err := r.db.write(ctx, func() error {
+		for _, id := range userIDs {
+			u, ok := r.db.users[id]
+			if !ok {
+				continue
+			}
+			u.IsActive = isActive
+			r.db.users[id] = u
+			users = append(users, u)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *UserRepository) AddOutOfOffice(
+	ctx context.Context,
+	ooo domain.OutOfOffice,
+) (domain.OutOfOffice, error) {
+	err := r.db.write(ctx, func() error {
+		if _, ok := r.db.users[ooo.UserID]; !ok {
+			return domain.ErrNotFound
+		}
+		r.db.lastOutOfOfficeID++
+		ooo.ID = r.db.lastOutOfOfficeID
+		r.db.outOfOffice[ooo.ID] = outOfOfficeRow{OutOfOffice: ooo}
+		return nil
+	})
+	if err != nil {
+		return domain.OutOfOffice{}, err
+	}
+	return ooo, nil
+}
+
+func (r *UserRepository) GetOutOfOfficeUsers(
+	ctx context.Context,
+	userIDs []string,
+	at time.Time,
+) ([]string, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	ids := make([]string, 0)
+	for _, row := range r.db.outOfOffice {
+		if row.covers(at) && slices.Contains(userIDs, row.UserID) && !slices.Contains(ids, row.UserID) {
+			ids = append(ids, row.UserID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *UserRepository) GetStartedOutOfOffice(
+	ctx context.Context,
+	at time.Time,
+) ([]domain.OutOfOffice, error) {
+	unlock := r.db.rlock(ctx)
+	defer unlock()
+
+	windows := make([]domain.OutOfOffice, 0)
+	for _, row := range r.db.outOfOffice {
+		if row.reassignedAt == nil && row.covers(at) {
+			windows = append(windows, row.OutOfOffice)
+		}
+	}
+	slices.SortFunc(windows, func(a, b domain.OutOfOffice) int {
+		if c := a.Start.Compare(b.Start); c != 0 {
+			return c
+		}
+		return int(a.ID - b.ID)
+	})
+	return windows, nil
+}
+
+func (r *UserRepository) MarkOutOfOfficeReassigned(
+	ctx context.Context,
+	id int64,
+	at time.Time,
+) (bool, error) {
+	var marked bool
+	err := r.db.write(ctx, func() error {
+		row, ok := r.db.outOfOffice[id]
+		if !ok || row.reassignedAt != nil {
+			return nil
+		}
+		row.reassignedAt = &at
+		r.db.outOfOffice[id] = row
+		marked = true
+		return nil
+	})
+	return marked, err
+}
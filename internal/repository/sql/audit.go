@@ -0,0 +1,137 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/ynsssss/pr-manager/internal/domain"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// AddEvents inserts the events with a single statement, whatever their number
+func (r *AuditRepository) AddEvents(ctx context.Context, events []domain.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var (
+		eventTypes, entityTypes, entityIDs, actors []string
+		before, after                              []sql.NullString
+	)
+	for _, event := range events {
+		eventTypes = append(eventTypes, string(event.EventType))
+		entityTypes = append(entityTypes, string(event.EntityType))
+		entityIDs = append(entityIDs, event.EntityID)
+		actors = append(actors, event.Actor)
+		before = append(before, nullJSON(event.Before))
+		after = append(after, nullJSON(event.After))
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`INSERT INTO audit_events (event_type, entity_type, entity_id, actor, before, after)
+		 SELECT e.event_type, e.entity_type, e.entity_id, e.actor, e.before, e.after
+		   FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::jsonb[], $6::jsonb[])
+		        WITH ORDINALITY AS e(event_type, entity_type, entity_id, actor, before, after, n)
+		  ORDER BY e.n`,
+		pq.Array(eventTypes),
+		pq.Array(entityTypes),
+		pq.Array(entityIDs),
+		pq.Array(actors),
+		pq.Array(before),
+		pq.Array(after),
+	)
+	return err
+}
+
+// nullJSON stores a nil document as NULL
+// rather than as an empty, invalid JSONB value
+func nullJSON(data json.RawMessage) sql.NullString {
+	return sql.NullString{String: string(data), Valid: data != nil}
+}
+
+// ListEvents returns up to filter.Limit events ordered by ID, newest first
+func (r *AuditRepository) ListEvents(
+	ctx context.Context,
+	filter domain.AuditFilter,
+) ([]domain.AuditEvent, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.EntityType != "" {
+		conds = append(conds, "entity_type = "+arg(filter.EntityType))
+	}
+	if filter.EntityID != "" {
+		conds = append(conds, "entity_id = "+arg(filter.EntityID))
+	}
+	if filter.From != nil {
+		conds = append(conds, "created_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conds = append(conds, "created_at < "+arg(*filter.To))
+	}
+	if filter.After != nil {
+		afterID, err := filter.After.EventID()
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, "audit_event_id < "+arg(afterID))
+	}
+
+	query := `SELECT audit_event_id, event_type, entity_type, entity_id,
+	                 actor, before, after, created_at
+	            FROM audit_events`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	query += ` ORDER BY audit_event_id DESC LIMIT ` + arg(filter.Limit)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]domain.AuditEvent, 0, filter.Limit)
+	for rows.Next() {
+		var (
+			event         domain.AuditEvent
+			before, after []byte
+		)
+		err := rows.Scan(
+			&event.ID,
+			&event.EventType,
+			&event.EntityType,
+			&event.EntityID,
+			&event.Actor,
+			&before,
+			&after,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		event.Before = before
+		event.After = after
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
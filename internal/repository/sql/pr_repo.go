@@ -3,13 +3,45 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
 	"github.com/ynsssss/pr-manager/internal/domain"
 )
 
+// prColumns selects a pull request aliased as p, the assigned
+// reviewers are aggregated from pull_request_reviewers.
+// Rows of reviewers that were replaced are kept with the
+// UNASSIGNED state, so the table also records who used to
+// review the PR. The state is spelled out in the queries
+// for the planner to match the partial index.
+// Reviews are aggregated into a JSON array of domain.Review.
+const prColumns = `
+	p.pull_request_id, p.pull_request_name, p.author_id, p.status,
+	ARRAY(
+		SELECT r.user_id
+		  FROM pull_request_reviewers r
+		 WHERE r.pull_request_id = p.pull_request_id
+		   AND r.state = 'ASSIGNED'
+		 ORDER BY r.assigned_at, r.user_id
+	),
+	p.created_at, p.merged_at,
+	COALESCE((
+		SELECT json_agg(json_build_object(
+		           'reviewer_id', rv.reviewer_id,
+		           'verdict', rv.verdict,
+		           'created_at', rv.created_at
+		       ) ORDER BY rv.review_id)
+		  FROM pull_request_reviews rv
+		 WHERE rv.pull_request_id = p.pull_request_id
+	), '[]'),
+	p.merge_forced_by`
+
 type PullRequestRepository struct {
 	db *sql.DB
 }
@@ -24,38 +56,44 @@ func (r *PullRequestRepository) Create(
 ) (*domain.PullRequest, error) {
 	query := `
 INSERT INTO pull_requests
-    (pull_request_id, pull_request_name, author_id, status, assigned_reviewers, created_at)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING pull_request_id, pull_request_name, author_id, status, assigned_reviewers, created_at, merged_at
+    (pull_request_id, pull_request_name, author_id, status, created_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING pull_request_id, pull_request_name, author_id, status, created_at, merged_at
 `
 
 	newPR.CreatedAt = time.Now()
 
 	var pr domain.PullRequest
-	var assigned pq.StringArray
-	err := r.db.QueryRowContext(
-		ctx,
-		query,
-		newPR.ID,
-		newPR.Name,
-		newPR.AuthorID,
-		newPR.Status,
-		pq.Array(newPR.AssignedReviewers),
-		newPR.CreatedAt,
-	).Scan(
-		&pr.ID,
-		&pr.Name,
-		&pr.AuthorID,
-		&pr.Status,
-		&assigned,
-		&pr.CreatedAt,
-		&pr.MergedAt,
-	)
-	pr.AssignedReviewers = []string(assigned)
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			newPR.ID,
+			newPR.Name,
+			newPR.AuthorID,
+			newPR.Status,
+			newPR.CreatedAt,
+		).Scan(
+			&pr.ID,
+			&pr.Name,
+			&pr.AuthorID,
+			&pr.Status,
+			&pr.CreatedAt,
+			&pr.MergedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		return syncReviewers(ctx, tx, pr.ID, newPR.AssignedReviewers)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	pr.AssignedReviewers = append([]string{}, newPR.AssignedReviewers...)
+	pr.Reviews = []domain.Review{}
+
 	return &pr, nil
 }
 
@@ -63,27 +101,14 @@ func (r *PullRequestRepository) GetByID(
 	ctx context.Context,
 	prID string,
 ) (*domain.PullRequest, error) {
-	row := r.db.QueryRowContext(
+	row := conn(ctx, r.db).QueryRowContext(
 		ctx,
-		`SELECT pull_request_id, pull_request_name, author_id, status,
-		        assigned_reviewers, created_at, merged_at
-		   FROM pull_requests WHERE pull_request_id = $1`,
+		`SELECT `+prColumns+`
+		   FROM pull_requests p WHERE p.pull_request_id = $1`,
 		prID,
 	)
 
-	var pr domain.PullRequest
-	var assigned pq.StringArray
-	var mergedAt sql.NullTime
-
-	err := row.Scan(
-		&pr.ID,
-		&pr.Name,
-		&pr.AuthorID,
-		&pr.Status,
-		&assigned,
-		&pr.CreatedAt,
-		&mergedAt,
-	)
+	pr, err := scanPullRequest(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, domain.ErrNotFound
@@ -91,95 +116,154 @@ func (r *PullRequestRepository) GetByID(
 		return nil, err
 	}
 
-	pr.AssignedReviewers = []string(assigned)
-
-	if mergedAt.Valid {
-		pr.MergedAt = &mergedAt.Time
-	}
-
-	return &pr, nil
+	return pr, nil
 }
 
 // UpdateWithFn is used to query a pr and update it's value
 // in a single transaction based on the business logic
-// provided with closure function
+// provided with closure function. It joins the ambient
+// transaction if the context carries one. The row stays locked
+// until the transaction ends, so concurrent updates of one PR
+// are applied one after another.
+// Reviews are append-only: only the ones appended by updateFn
+// are stored.
 func (r *PullRequestRepository) UpdateWithFn(
 	ctx context.Context,
 	id string,
 	updateFn func(pr *domain.PullRequest) (*domain.PullRequest, error),
 ) (*domain.PullRequest, error) {
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
+	var pr *domain.PullRequest
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		pr, err = r.getByIDTx(ctx, tx, id)
 		if err != nil {
-			_ = tx.Rollback()
+			return err
 		}
-	}()
+		storedReviews := len(pr.Reviews)
 
-	pr, err := r.getByIDTx(ctx, tx, id)
-	if err != nil {
-		return nil, err
-	}
+		pr, err = updateFn(pr)
+		if err != nil {
+			return err
+		}
 
-	pr, err = updateFn(pr)
-	if err != nil {
-		return nil, err
-	}
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE pull_requests
+			 SET pull_request_name = $1, status = $2, merged_at = $3, merge_forced_by = $4
+			 WHERE pull_request_id = $5`,
+			pr.Name, pr.Status, pr.MergedAt, sql.NullString{
+				String: pr.MergeForcedBy,
+				Valid:  pr.MergeForcedBy != "",
+			}, pr.ID,
+		)
+		if err != nil {
+			return err
+		}
 
-	assigned := pq.StringArray(pr.AssignedReviewers)
+		if err := syncReviewers(ctx, tx, pr.ID, pr.AssignedReviewers); err != nil {
+			return err
+		}
 
-	_, err = tx.ExecContext(
-		ctx,
-		`UPDATE pull_requests
-		 SET pull_request_name = $1, status = $2, assigned_reviewers = $3, merged_at = $4
-		 WHERE pull_request_id = $5`,
-		pr.Name, pr.Status, assigned, pr.MergedAt, pr.ID,
-	)
+		return insertReviews(ctx, tx, pr.ID, pr.Reviews[storedReviews:])
+	})
 	if err != nil {
-		_ = tx.Rollback()
-		return nil, err
-	}
-
-	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
 	return pr, nil
 }
 
+// getByIDTx reads the PR locking its row for update.
+// The lock is taken by its own statement: under READ COMMITTED
+// a locking SELECT re-reads only the locked row after waiting,
+// its reviewer and review subqueries would still see the snapshot
+// taken before the wait. The second statement gets a fresh one.
 func (r *PullRequestRepository) getByIDTx(
 	ctx context.Context,
 	tx *sql.Tx,
 	prID string,
 ) (*domain.PullRequest, error) {
-	row := tx.QueryRowContext(
+	var locked int
+	err := tx.QueryRowContext(
 		ctx,
-		`SELECT pull_request_id, pull_request_name, author_id, status, assigned_reviewers, created_at, merged_at
-		 FROM pull_requests
-		 WHERE pull_request_id = $1`,
+		`SELECT 1 FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE`,
 		prID,
-	)
-
-	var pr domain.PullRequest
-	var assigned pq.StringArray
-	var mergedAt sql.NullTime
-
-	if err := row.Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status, &assigned, &pr.CreatedAt, &mergedAt); err != nil {
+	).Scan(&locked)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, domain.ErrNotFound
 		}
 		return nil, err
 	}
 
-	pr.AssignedReviewers = []string(assigned)
+	row := tx.QueryRowContext(
+		ctx,
+		`SELECT `+prColumns+`
+		 FROM pull_requests p
+		 WHERE p.pull_request_id = $1`,
+		prID,
+	)
 
-	if mergedAt.Valid {
-		pr.MergedAt = &mergedAt.Time
-	}
+	return scanPullRequest(row)
+}
 
-	return &pr, nil
+// syncReviewers makes reviewers the assigned reviewers of the PR.
+// Reviewers that stay assigned keep their assigned_at. Reviewers that
+// join take the assigned_at of the ones that left, in order, so a
+// replacement keeps the place of the reviewer it replaced like in the
+// in-memory repository. The rest are appended.
+func syncReviewers(ctx context.Context, tx *sql.Tx, prID string, reviewers []string) error {
+	// assigned_at grows with the position to keep the order of appended reviewers
+	_, err := tx.ExecContext(
+		ctx,
+		`WITH unassigned AS (
+		     UPDATE pull_request_reviewers
+		        SET state = 'UNASSIGNED'
+		      WHERE pull_request_id = $1
+		        AND state = 'ASSIGNED'
+		        AND NOT (user_id = ANY($2))
+		  RETURNING user_id, assigned_at
+		 ), freed AS (
+		     SELECT assigned_at, row_number() OVER (ORDER BY assigned_at, user_id) AS slot
+		       FROM unassigned
+		 ), joining AS (
+		     SELECT r.user_id, r.position, row_number() OVER (ORDER BY r.position) AS slot
+		       FROM unnest($2::text[]) WITH ORDINALITY AS r(user_id, position)
+		      WHERE NOT EXISTS (
+		            SELECT 1
+		              FROM pull_request_reviewers a
+		             WHERE a.pull_request_id = $1
+		               AND a.user_id = r.user_id
+		               AND a.state = 'ASSIGNED'
+		      )
+		 )
+		 INSERT INTO pull_request_reviewers (pull_request_id, user_id, assigned_at, state)
+		 SELECT $1, j.user_id,
+		        COALESCE(f.assigned_at, NOW() + j.position * INTERVAL '1 microsecond'),
+		        'ASSIGNED'
+		   FROM joining j
+		   LEFT JOIN freed f ON f.slot = j.slot
+		 ON CONFLICT (pull_request_id, user_id) DO UPDATE
+		    SET state = 'ASSIGNED', assigned_at = EXCLUDED.assigned_at
+		  WHERE pull_request_reviewers.state <> 'ASSIGNED'`,
+		prID, pq.Array(reviewers),
+	)
+	return err
+}
+
+func insertReviews(ctx context.Context, tx *sql.Tx, prID string, reviews []domain.Review) error {
+	for _, review := range reviews {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO pull_request_reviews (pull_request_id, reviewer_id, verdict, created_at)
+			 VALUES ($1, $2, $3, $4)`,
+			prID, review.ReviewerID, review.Verdict, review.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (r *PullRequestRepository) GetPullRequestsForUser(
@@ -187,11 +271,12 @@ func (r *PullRequestRepository) GetPullRequestsForUser(
 	userID string,
 ) ([]domain.PullRequest, error) {
 
-	rows, err := r.db.QueryContext(
+	rows, err := conn(ctx, r.db).QueryContext(
 		ctx,
-		`SELECT pull_request_id, pull_request_name, author_id, status, assigned_reviewers, created_at, merged_at
-         FROM pull_requests
-         WHERE $1 = ANY(assigned_reviewers)`,
+		`SELECT `+prColumns+`
+         FROM pull_request_reviewers pr_r
+         JOIN pull_requests p ON p.pull_request_id = pr_r.pull_request_id
+         WHERE pr_r.user_id = $1 AND pr_r.state = 'ASSIGNED'`,
 		userID,
 	)
 	if err != nil {
@@ -202,30 +287,323 @@ func (r *PullRequestRepository) GetPullRequestsForUser(
 	var prs []domain.PullRequest
 
 	for rows.Next() {
-		var pr domain.PullRequest
-		var assignedReviewers []string
-		var mergedAt sql.NullTime
-
-		if err := rows.Scan(
-			&pr.ID,
-			&pr.Name,
-			&pr.AuthorID,
-			&pr.Status,
-			pq.Array(&assignedReviewers),
-			&pr.CreatedAt,
-			&mergedAt,
-		); err != nil {
+		pr, err := scanPullRequest(rows)
+		if err != nil {
 			return nil, err
 		}
 
-		if mergedAt.Valid {
-			pr.MergedAt = &mergedAt.Time
+		prs = append(prs, *pr)
+	}
+
+	return prs, rows.Err()
+}
+
+// ListPullRequests returns up to filter.Limit pull requests
+// ordered by creation time and ID, newest first
+func (r *PullRequestRepository) ListPullRequests(
+	ctx context.Context,
+	filter domain.PullRequestFilter,
+) ([]domain.PullRequest, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.Status != "" {
+		conds = append(conds, "p.status = "+arg(filter.Status))
+	}
+	if filter.AuthorID != "" {
+		conds = append(conds, "p.author_id = "+arg(filter.AuthorID))
+	}
+	if filter.ReviewerID != "" {
+		conds = append(conds, `EXISTS (
+			SELECT 1
+			  FROM pull_request_reviewers r
+			 WHERE r.pull_request_id = p.pull_request_id
+			   AND r.user_id = `+arg(filter.ReviewerID)+`
+			   AND r.state = 'ASSIGNED'
+		)`)
+	}
+	if filter.TeamName != "" {
+		conds = append(conds, `p.author_id IN (
+			SELECT user_id FROM users WHERE team_name = `+arg(filter.TeamName)+`
+		)`)
+	}
+	if filter.CreatedFrom != nil {
+		conds = append(conds, "p.created_at >= "+arg(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		conds = append(conds, "p.created_at < "+arg(*filter.CreatedTo))
+	}
+	if filter.MergedFrom != nil {
+		conds = append(conds, "p.merged_at >= "+arg(*filter.MergedFrom))
+	}
+	if filter.MergedTo != nil {
+		conds = append(conds, "p.merged_at < "+arg(*filter.MergedTo))
+	}
+	if filter.After != nil {
+		conds = append(conds, fmt.Sprintf(
+			"(p.created_at, p.pull_request_id) < (%s, %s)",
+			arg(filter.After.Time), arg(filter.After.ID),
+		))
+	}
+
+	query := `SELECT ` + prColumns + ` FROM pull_requests p`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	query += ` ORDER BY p.created_at DESC, p.pull_request_id DESC LIMIT ` + arg(filter.Limit)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prs := make([]domain.PullRequest, 0, filter.Limit)
+	for rows.Next() {
+		pr, err := scanPullRequest(rows)
+		if err != nil {
+			return nil, err
 		}
+		prs = append(prs, *pr)
+	}
 
-		pr.AssignedReviewers = assignedReviewers
+	return prs, rows.Err()
+}
 
-		prs = append(prs, pr)
+// GetOpenPullRequestsForReviewers returns the OPEN pull requests
+// assigned to any of the users, locking them for update.
+// Like getByIDTx it locks first and reads the locked PRs with a
+// second statement, so their reviewers aren't read from before
+// the lock wait.
+func (r *PullRequestRepository) GetOpenPullRequestsForReviewers(
+	ctx context.Context,
+	userIDs []string,
+) ([]domain.PullRequest, error) {
+	var prs []domain.PullRequest
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		locked, err := lockOpenPullRequestsForReviewers(ctx, tx, userIDs)
+		if err != nil {
+			return err
+		}
+
+		// the locked PRs are filtered again, the users
+		// may have been replaced while the lock was awaited
+		rows, err := tx.QueryContext(
+			ctx,
+			`SELECT `+prColumns+`
+			 FROM pull_requests p
+			 WHERE p.pull_request_id = ANY($1)
+			   AND p.status = $2
+			   AND EXISTS (
+			       SELECT 1
+			         FROM pull_request_reviewers pr_r
+			        WHERE pr_r.pull_request_id = p.pull_request_id
+			          AND pr_r.user_id = ANY($3) AND pr_r.state = 'ASSIGNED'
+			   )
+			 ORDER BY p.pull_request_id`,
+			pq.Array(locked), domain.StatusOpen, pq.Array(userIDs),
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		prs = make([]domain.PullRequest, 0, len(locked))
+		for rows.Next() {
+			pr, err := scanPullRequest(rows)
+			if err != nil {
+				return err
+			}
+			prs = append(prs, *pr)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return prs, nil
 }
+
+// lockOpenPullRequestsForReviewers locks the OPEN pull requests
+// assigned to any of the users and returns their IDs
+func lockOpenPullRequestsForReviewers(
+	ctx context.Context,
+	tx *sql.Tx,
+	userIDs []string,
+) ([]string, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT p.pull_request_id
+		 FROM pull_requests p
+		 WHERE p.status = $1
+		   AND p.pull_request_id IN (
+		       SELECT pr_r.pull_request_id
+		         FROM pull_request_reviewers pr_r
+		        WHERE pr_r.user_id = ANY($2) AND pr_r.state = 'ASSIGNED'
+		   )
+		 ORDER BY p.pull_request_id
+		 FOR UPDATE OF p`,
+		domain.StatusOpen, pq.Array(userIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prIDs []string
+	for rows.Next() {
+		var prID string
+		if err := rows.Scan(&prID); err != nil {
+			return nil, err
+		}
+		prIDs = append(prIDs, prID)
+	}
+
+	return prIDs, rows.Err()
+}
+
+// ReplaceReviewers applies the reassignments that have a new reviewer
+// with a single statement, whatever their number. A new reviewer is
+// only assigned where the old one was still assigned, so a stale
+// reassignment can't grow the PR past its reviewers. It takes the
+// old reviewer's assigned_at to keep its place among the reviewers.
+func (r *PullRequestRepository) ReplaceReviewers(
+	ctx context.Context,
+	reassignments []domain.Reassignment,
+) error {
+	var prIDs, oldIDs, newIDs []string
+	for _, reassignment := range reassignments {
+		if reassignment.NewReviewerID == "" {
+			continue
+		}
+		prIDs = append(prIDs, reassignment.PullRequestID)
+		oldIDs = append(oldIDs, reassignment.OldReviewerID)
+		newIDs = append(newIDs, reassignment.NewReviewerID)
+	}
+	if len(prIDs) == 0 {
+		return nil
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`WITH unassigned AS (
+		     UPDATE pull_request_reviewers r
+		        SET state = 'UNASSIGNED'
+		       FROM unnest($1::text[], $2::text[], $3::text[])
+		            AS c(pull_request_id, old_user_id, new_user_id)
+		      WHERE r.pull_request_id = c.pull_request_id
+		        AND r.user_id = c.old_user_id
+		        AND r.state = 'ASSIGNED'
+		  RETURNING r.pull_request_id, c.new_user_id, r.assigned_at
+		 )
+		 INSERT INTO pull_request_reviewers (pull_request_id, user_id, assigned_at, state)
+		 SELECT u.pull_request_id, u.new_user_id, u.assigned_at, 'ASSIGNED'
+		   FROM unassigned u
+		 ON CONFLICT (pull_request_id, user_id) DO UPDATE
+		    SET state = 'ASSIGNED', assigned_at = EXCLUDED.assigned_at
+		  WHERE pull_request_reviewers.state <> 'ASSIGNED'`,
+		pq.Array(prIDs), pq.Array(oldIDs), pq.Array(newIDs),
+	)
+	return err
+}
+
+func (r *PullRequestRepository) CountOpenReviews(
+	ctx context.Context,
+	userIDs []string,
+) (map[string]int, error) {
+	rows, err := conn(ctx, r.db).QueryContext(
+		ctx,
+		`SELECT r.user_id, COUNT(*)
+		 FROM pull_request_reviewers r
+		 JOIN pull_requests p ON p.pull_request_id = r.pull_request_id
+		 WHERE r.user_id = ANY($1) AND r.state = 'ASSIGNED' AND p.status = $2
+		 GROUP BY r.user_id`,
+		pq.Array(userIDs), domain.StatusOpen,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	load := make(map[string]int, len(userIDs))
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, err
+		}
+		load[userID] = count
+	}
+
+	return load, rows.Err()
+}
+
+func (r *PullRequestRepository) HasOpenPullRequests(
+	ctx context.Context,
+	userIDs []string,
+) (bool, error) {
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		`SELECT EXISTS (
+		     SELECT 1
+		     FROM pull_requests
+		     WHERE author_id = ANY($1) AND status IN ($2, $3)
+		 ) OR EXISTS (
+		     SELECT 1
+		     FROM pull_request_reviewers r
+		     JOIN pull_requests p ON p.pull_request_id = r.pull_request_id
+		     WHERE r.user_id = ANY($1) AND r.state = 'ASSIGNED' AND p.status = $2
+		 )`,
+		pq.Array(userIDs), domain.StatusOpen, domain.StatusDraft,
+	).Scan(&exists)
+	return exists, err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanPullRequest scans a row selected with prColumns
+func scanPullRequest(row scanner) (*domain.PullRequest, error) {
+	var pr domain.PullRequest
+	var assigned pq.StringArray
+	var mergedAt sql.NullTime
+	var reviews []byte
+	var mergeForcedBy sql.NullString
+
+	err := row.Scan(
+		&pr.ID,
+		&pr.Name,
+		&pr.AuthorID,
+		&pr.Status,
+		&assigned,
+		&pr.CreatedAt,
+		&mergedAt,
+		&reviews,
+		&mergeForcedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(reviews, &pr.Reviews); err != nil {
+		return nil, err
+	}
+
+	pr.AssignedReviewers = []string(assigned)
+	pr.MergeForcedBy = mergeForcedBy.String
+
+	if mergedAt.Valid {
+		pr.MergedAt = &mergedAt.Time
+	}
+
+	return &pr, nil
+}
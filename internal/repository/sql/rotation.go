@@ -0,0 +1,58 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+)
+
+type RotationRepository struct {
+	db *sql.DB
+}
+
+func NewRotationRepository(db *sql.DB) *RotationRepository {
+	return &RotationRepository{db: db}
+}
+
+// UpdateCursorWithFn locks the rotation row of the team with
+// SELECT ... FOR UPDATE, so concurrent callers advance the
+// cursor one after another
+func (r *RotationRepository) UpdateCursorWithFn(
+	ctx context.Context,
+	teamName string,
+	updateFn func(lastUserID string) (string, error),
+) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO team_rotations (team_name, last_user_id)
+			 VALUES ($1, '')
+			 ON CONFLICT (team_name) DO NOTHING`,
+			teamName,
+		)
+		if err != nil {
+			return err
+		}
+
+		var lastUserID string
+		err = tx.QueryRowContext(
+			ctx,
+			`SELECT last_user_id FROM team_rotations WHERE team_name = $1 FOR UPDATE`,
+			teamName,
+		).Scan(&lastUserID)
+		if err != nil {
+			return err
+		}
+
+		lastUserID, err = updateFn(lastUserID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE team_rotations SET last_user_id = $1 WHERE team_name = $2`,
+			lastUserID, teamName,
+		)
+		return err
+	})
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"slices"
 
+	"github.com/lib/pq"
 	"github.com/ynsssss/pr-manager/internal/domain"
 )
 
@@ -18,7 +20,7 @@ func NewTeamRepository(db *sql.DB) *TeamRepository {
 
 func (r *TeamRepository) TeamExists(ctx context.Context, name string) (bool, error) {
 	var exists bool
-	err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM teams WHERE team_name = $1)`, name).
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM teams WHERE team_name = $1)`, name).
 		Scan(&exists)
 	return exists, err
 }
@@ -27,7 +29,32 @@ func (r *TeamRepository) CreateTeam(
 	ctx context.Context,
 	team *domain.Team,
 ) (*domain.Team, error) {
-	_, err := r.db.ExecContext(ctx, `INSERT INTO teams (team_name) VALUES ($1)`, team.Name)
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO teams (team_name, parent_team_name) VALUES ($1, NULLIF($2, ''))`,
+			team.Name, team.ParentTeam,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO team_settings (
+			     team_name, min_reviewers, max_reviewers, required_approvals,
+			     default_max_open_reviews, capacity_policy
+			 )
+			 VALUES ($1, $2, $3, $4, $5, $6)`,
+			team.Name, team.MinReviewers, team.MaxReviewers, team.RequiredApprovals,
+			team.DefaultMaxOpenReviews, team.CapacityPolicy,
+		)
+		if err != nil {
+			return err
+		}
+
+		return replaceFallbacks(ctx, tx, team.Name, team.FallbackTeams)
+	})
 	return team, err
 }
 
@@ -35,18 +62,43 @@ func (r *TeamRepository) GetTeamByName(
 	ctx context.Context,
 	teamName string,
 ) (*domain.Team, error) {
-	row := r.db.QueryRowContext(ctx, `SELECT team_name FROM teams WHERE team_name = $1`, teamName)
+	row := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		`SELECT COALESCE(t.parent_team_name, ''),
+		        COALESCE(s.min_reviewers, $2),
+		        COALESCE(s.max_reviewers, $3),
+		        COALESCE(s.required_approvals, $4),
+		        COALESCE(s.default_max_open_reviews, 0),
+		        COALESCE(s.capacity_policy, $5)
+		   FROM teams t
+		   LEFT JOIN team_settings s ON s.team_name = t.team_name
+		  WHERE t.team_name = $1`,
+		teamName,
+		domain.DefaultMinReviewers,
+		domain.DefaultMaxReviewers,
+		domain.DefaultRequiredApprovals,
+		domain.CapacityAssignFewer,
+	)
 
-	var name string
-	if err := row.Scan(&name); err != nil {
+	var parentTeam string
+	var settings domain.TeamSettings
+	err := row.Scan(
+		&parentTeam,
+		&settings.MinReviewers,
+		&settings.MaxReviewers,
+		&settings.RequiredApprovals,
+		&settings.DefaultMaxOpenReviews,
+		&settings.CapacityPolicy,
+	)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, domain.ErrNotFound
 		}
 		return nil, err
 	}
 
-	rows, err := r.db.QueryContext(ctx, `
-		SELECT user_id, username, is_active
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT user_id, username, is_active, max_open_reviews
 		FROM users
 		WHERE team_name = $1
 	`, teamName)
@@ -58,26 +110,348 @@ func (r *TeamRepository) GetTeamByName(
 	members := make([]domain.TeamMember, 0)
 	for rows.Next() {
 		var m domain.TeamMember
-		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive); err != nil {
+		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive, &m.MaxOpenReviews); err != nil {
 			return nil, err
 		}
 		members = append(members, m)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// a transaction can't run the next query while rows are open
+	rows.Close()
+
+	settings.FallbackTeams, err = getFallbacks(ctx, conn(ctx, r.db), teamName)
+	if err != nil {
+		return nil, err
+	}
 
 	return &domain.Team{
-		Name:    teamName,
-		Members: members,
+		Name:         teamName,
+		Members:      members,
+		ParentTeam:   parentTeam,
+		TeamSettings: settings,
 	}, nil
 }
 
+// ListTeamStats aggregates all teams in a single query,
+// review load is averaged over active members only
+func (r *TeamRepository) ListTeamStats(ctx context.Context) ([]domain.TeamStats, error) {
+	rows, err := conn(ctx, r.db).QueryContext(
+		ctx,
+		`WITH review_load AS (
+		     SELECT r.user_id, COUNT(*) AS open_reviews
+		     FROM pull_request_reviewers r
+		     JOIN pull_requests p ON p.pull_request_id = r.pull_request_id
+		     WHERE r.state = 'ASSIGNED' AND p.status = $1
+		     GROUP BY r.user_id
+		 ), authored AS (
+		     SELECT u.team_name, COUNT(*) AS open_prs
+		     FROM pull_requests p
+		     JOIN users u ON u.user_id = p.author_id
+		     WHERE p.status = $1
+		     GROUP BY u.team_name
+		 )
+		 SELECT t.team_name,
+		        COALESCE(t.parent_team_name, ''),
+		        COALESCE(s.min_reviewers, $2),
+		        COUNT(u.user_id),
+		        COUNT(u.user_id) FILTER (WHERE u.is_active),
+		        COALESCE(a.open_prs, 0),
+		        COALESCE(AVG(COALESCE(l.open_reviews, 0)) FILTER (WHERE u.is_active), 0)::float8
+		   FROM teams t
+		   LEFT JOIN team_settings s ON s.team_name = t.team_name
+		   LEFT JOIN users u ON u.team_name = t.team_name
+		   LEFT JOIN review_load l ON l.user_id = u.user_id
+		   LEFT JOIN authored a ON a.team_name = t.team_name
+		  GROUP BY t.team_name, t.parent_team_name, s.min_reviewers, a.open_prs
+		  ORDER BY t.team_name`,
+		domain.StatusOpen, domain.DefaultMinReviewers,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]domain.TeamStats, 0)
+	for rows.Next() {
+		var s domain.TeamStats
+		err := rows.Scan(
+			&s.TeamName,
+			&s.ParentTeam,
+			&s.MinReviewers,
+			&s.MemberCount,
+			&s.ActiveMemberCount,
+			&s.OpenPRCount,
+			&s.AvgReviewLoad,
+		)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetTeamWithSubteams returns the team with the members of all
+// its sub-teams, every member carries the name of their own team
+func (r *TeamRepository) GetTeamWithSubteams(
+	ctx context.Context,
+	teamName string,
+) (*domain.Team, error) {
+	team, err := r.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range team.Members {
+		team.Members[i].TeamName = teamName
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		WITH RECURSIVE subteams AS (
+			SELECT team_name FROM teams WHERE parent_team_name = $1
+			UNION
+			SELECT t.team_name
+			FROM teams t
+			JOIN subteams s ON t.parent_team_name = s.team_name
+			WHERE t.team_name <> $1
+		)
+		SELECT u.user_id, u.username, u.is_active, u.max_open_reviews, u.team_name
+		FROM users u
+		JOIN subteams s ON s.team_name = u.team_name
+		ORDER BY u.team_name, u.user_id
+	`, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m domain.TeamMember
+		err := rows.Scan(&m.UserID, &m.Username, &m.IsActive, &m.MaxOpenReviews, &m.TeamName)
+		if err != nil {
+			return nil, err
+		}
+		team.Members = append(team.Members, m)
+	}
+
+	return team, rows.Err()
+}
+
+// SetParentTeam makes the team a sub-team of parentTeam,
+// an empty parentTeam makes it a top-level team
+func (r *TeamRepository) SetParentTeam(ctx context.Context, teamName, parentTeam string) error {
+	res, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE teams SET parent_team_name = NULLIF($2, '') WHERE team_name = $1`,
+		teamName, parentTeam,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// LockAncestors walks up from the team locking every row it
+// reads, a parent change committed while a lock was awaited
+// is seen by the locking read
+func (r *TeamRepository) LockAncestors(ctx context.Context, teamName string) ([]string, error) {
+	var ancestors []string
+	for name := teamName; name != ""; {
+		if slices.Contains(ancestors, name) {
+			return nil, domain.ErrTeamCycle
+		}
+
+		var parent sql.NullString
+		err := conn(ctx, r.db).QueryRowContext(
+			ctx,
+			`SELECT parent_team_name FROM teams WHERE team_name = $1 FOR UPDATE`,
+			name,
+		).Scan(&parent)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, domain.ErrNotFound
+			}
+			return nil, err
+		}
+
+		ancestors = append(ancestors, name)
+		name = parent.String
+	}
+	return ancestors, nil
+}
+
+// UpdateSettingsWithFn locks the settings of the team, applies
+// updateFn to them and stores the result
+func (r *TeamRepository) UpdateSettingsWithFn(
+	ctx context.Context,
+	teamName string,
+	updateFn func(settings *domain.TeamSettings) error,
+) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO team_settings (team_name)
+			 SELECT team_name FROM teams WHERE team_name = $1
+			 ON CONFLICT (team_name) DO NOTHING`,
+			teamName,
+		)
+		if err != nil {
+			return err
+		}
+
+		var settings domain.TeamSettings
+		err = tx.QueryRowContext(
+			ctx,
+			`SELECT min_reviewers, max_reviewers, required_approvals,
+			        default_max_open_reviews, capacity_policy
+			   FROM team_settings
+			  WHERE team_name = $1
+			    FOR UPDATE`,
+			teamName,
+		).Scan(
+			&settings.MinReviewers,
+			&settings.MaxReviewers,
+			&settings.RequiredApprovals,
+			&settings.DefaultMaxOpenReviews,
+			&settings.CapacityPolicy,
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return domain.ErrNotFound
+			}
+			return err
+		}
+
+		settings.FallbackTeams, err = getFallbacks(ctx, tx, teamName)
+		if err != nil {
+			return err
+		}
+
+		if err := updateFn(&settings); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE team_settings
+			    SET min_reviewers = $1, max_reviewers = $2, required_approvals = $3,
+			        default_max_open_reviews = $4, capacity_policy = $5
+			  WHERE team_name = $6`,
+			settings.MinReviewers, settings.MaxReviewers, settings.RequiredApprovals,
+			settings.DefaultMaxOpenReviews, settings.CapacityPolicy, teamName,
+		)
+		if err != nil {
+			return err
+		}
+
+		return replaceFallbacks(ctx, tx, teamName, settings.FallbackTeams)
+	})
+}
+
+// DeleteTeam deletes the team with its settings, rotation cursor
+// and fallbacks, members are left without a team
+func (r *TeamRepository) DeleteTeam(ctx context.Context, teamName string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM teams WHERE team_name = $1`, teamName)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// RenameTeam relies on ON UPDATE CASCADE to update the rows
+// referring to the team
+func (r *TeamRepository) RenameTeam(ctx context.Context, oldName, newName string) error {
+	res, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		`UPDATE teams SET team_name = $2 WHERE team_name = $1`,
+		oldName, newName,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return domain.ErrTeamExists
+		}
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func getFallbacks(ctx context.Context, q querier, teamName string) ([]string, error) {
+	rows, err := q.QueryContext(
+		ctx,
+		`SELECT fallback_team_name
+		   FROM team_fallbacks
+		  WHERE team_name = $1
+		  ORDER BY position`,
+		teamName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fallbacks := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		fallbacks = append(fallbacks, name)
+	}
+
+	return fallbacks, rows.Err()
+}
+
+func replaceFallbacks(ctx context.Context, tx *sql.Tx, teamName string, fallbacks []string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM team_fallbacks WHERE team_name = $1`, teamName)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO team_fallbacks (team_name, fallback_team_name, position)
+		 SELECT $1, name, position
+		   FROM unnest($2::text[]) WITH ORDINALITY AS f(name, position)`,
+		teamName, pq.Array(fallbacks),
+	)
+	return err
+}
+
 // TODO: move to userRepo and rename to GetUserTeam
 func (r *TeamRepository) GetTeamWithUser(
 	ctx context.Context,
 	userID string,
 ) (*domain.Team, error) {
-	row := r.db.QueryRowContext(
+	row := conn(ctx, r.db).QueryRowContext(
 		ctx,
-		`SELECT team_name FROM users WHERE user_id = $1`,
+		`SELECT COALESCE(team_name, '') FROM users WHERE user_id = $1`,
 		userID,
 	)
 	var teamName string
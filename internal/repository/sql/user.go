@@ -4,7 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/ynsssss/pr-manager/internal/domain"
 )
 
@@ -17,14 +21,14 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 }
 
 func (r *UserRepository) GetByID(ctx context.Context, userID string) (domain.User, error) {
-	row := r.db.QueryRowContext(ctx, `
-		SELECT user_id, username, team_name, is_active
+	row := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT user_id, username, COALESCE(team_name, ''), is_active, max_open_reviews
 		FROM users
 		WHERE user_id = $1
 	`, userID)
 
 	var u domain.User
-	if err := row.Scan(&u.ID, &u.Username, &u.TeamName, &u.IsActive); err != nil {
+	if err := row.Scan(&u.ID, &u.Username, &u.TeamName, &u.IsActive, &u.MaxOpenReviews); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return domain.User{}, domain.ErrNotFound
 		}
@@ -34,6 +38,82 @@ func (r *UserRepository) GetByID(ctx context.Context, userID string) (domain.Use
 	return u, nil
 }
 
+func (r *UserRepository) GetByIDs(ctx context.Context, userIDs []string) ([]domain.User, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT user_id, username, COALESCE(team_name, ''), is_active, max_open_reviews
+		FROM users
+		WHERE user_id = ANY($1)
+		ORDER BY user_id
+	`, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]domain.User, 0, len(userIDs))
+	for rows.Next() {
+		var u domain.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.TeamName, &u.IsActive, &u.MaxOpenReviews); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+// ListUsers returns up to filter.Limit users ordered by ID
+func (r *UserRepository) ListUsers(ctx context.Context, filter domain.UserFilter) ([]domain.User, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.TeamName != "" {
+		conds = append(conds, "team_name = "+arg(filter.TeamName))
+	}
+	if filter.IsActive != nil {
+		conds = append(conds, "is_active = "+arg(*filter.IsActive))
+	}
+	if filter.UsernamePrefix != "" {
+		prefix := likeEscaper.Replace(strings.ToLower(filter.UsernamePrefix))
+		conds = append(conds, "lower(username) LIKE "+arg(prefix+"%"))
+	}
+	if filter.After != nil {
+		conds = append(conds, "user_id > "+arg(filter.After.ID))
+	}
+
+	query := `SELECT user_id, username, COALESCE(team_name, ''), is_active, max_open_reviews FROM users`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	query += ` ORDER BY user_id LIMIT ` + arg(filter.Limit)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]domain.User, 0, filter.Limit)
+	for rows.Next() {
+		var u domain.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.TeamName, &u.IsActive, &u.MaxOpenReviews); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+// likeEscaper escapes the LIKE wildcards with the default escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 func (r *UserRepository) SetIsActive(
 	ctx context.Context,
 	userID string,
@@ -44,10 +124,10 @@ func (r *UserRepository) SetIsActive(
 		UPDATE users
 		SET is_active = $1
 		WHERE user_id = $2
-		RETURNING user_id, username, team_name, is_active
+		RETURNING user_id, username, COALESCE(team_name, ''), is_active, max_open_reviews
 	`
-	err := r.db.QueryRowContext(ctx, query, isActive, userID).
-		Scan(&u.ID, &u.Username, &u.TeamName, &u.IsActive)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, isActive, userID).
+		Scan(&u.ID, &u.Username, &u.TeamName, &u.IsActive, &u.MaxOpenReviews)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return domain.User{}, domain.ErrNotFound
@@ -57,30 +137,196 @@ func (r *UserRepository) SetIsActive(
 	return u, nil
 }
 
+// SetTeamForUsers moves the users to the team, an empty
+// team name detaches them from their team
+func (r *UserRepository) SetTeamForUsers(
+	ctx context.Context,
+	userIDs []string,
+	teamName string,
+) ([]domain.User, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		UPDATE users
+		SET team_name = NULLIF($1, '')
+		WHERE user_id = ANY($2)
+		RETURNING user_id, username, COALESCE(team_name, ''), is_active, max_open_reviews
+	`, teamName, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]domain.User, 0, len(userIDs))
+	for rows.Next() {
+		var u domain.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.TeamName, &u.IsActive, &u.MaxOpenReviews); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *UserRepository) SetMaxOpenReviews(
+	ctx context.Context,
+	userID string,
+	maxOpenReviews int,
+) (domain.User, error) {
+	var u domain.User
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		UPDATE users
+		SET max_open_reviews = $1
+		WHERE user_id = $2
+		RETURNING user_id, username, COALESCE(team_name, ''), is_active, max_open_reviews
+	`, maxOpenReviews, userID).
+		Scan(&u.ID, &u.Username, &u.TeamName, &u.IsActive, &u.MaxOpenReviews)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.User{}, domain.ErrNotFound
+		}
+		return domain.User{}, err
+	}
+	return u, nil
+}
+
 func (r *UserRepository) UpsertUsers(ctx context.Context, users []domain.User) error {
-	tx, err := r.db.BeginTx(ctx, nil)
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		for _, u := range users {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO users (user_id, username, team_name, is_active, max_open_reviews)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (user_id) DO UPDATE SET
+					username = EXCLUDED.username,
+					team_name = EXCLUDED.team_name,
+					is_active = EXCLUDED.is_active,
+					max_open_reviews = EXCLUDED.max_open_reviews
+			`, u.ID, u.Username, u.TeamName, u.IsActive, u.MaxOpenReviews)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *UserRepository) SetIsActiveForUsers(
+	ctx context.Context,
+	userIDs []string,
+	isActive bool,
+) ([]domain.User, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		UPDATE users
+		SET is_active = $1
+		WHERE user_id = ANY($2)
+		RETURNING user_id, username, COALESCE(team_name, ''), is_active, max_open_reviews
+	`, isActive, pq.Array(userIDs))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
+	defer rows.Close()
+
+	users := make([]domain.User, 0, len(userIDs))
+	for rows.Next() {
+		var u domain.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.TeamName, &u.IsActive, &u.MaxOpenReviews); err != nil {
+			return nil, err
 		}
-	}()
-
-	for _, u := range users {
-		_, err := tx.ExecContext(ctx, `
-			INSERT INTO users (user_id, username, team_name, is_active)
-			VALUES ($1, $2, $3, $4)
-			ON CONFLICT (user_id) DO UPDATE SET
-				username = EXCLUDED.username,
-				team_name = EXCLUDED.team_name,
-				is_active = EXCLUDED.is_active
-		`, u.ID, u.Username, u.TeamName, u.IsActive)
-		if err != nil {
-			return err
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *UserRepository) AddOutOfOffice(
+	ctx context.Context,
+	ooo domain.OutOfOffice,
+) (domain.OutOfOffice, error) {
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO user_out_of_office (user_id, starts_at, ends_at)
+		VALUES ($1, $2, $3)
+		RETURNING out_of_office_id
+	`, ooo.UserID, ooo.Start, ooo.End).Scan(&ooo.ID)
+	if err != nil {
+		return domain.OutOfOffice{}, err
+	}
+	return ooo, nil
+}
+
+func (r *UserRepository) GetOutOfOfficeUsers(
+	ctx context.Context,
+	userIDs []string,
+	at time.Time,
+) ([]string, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT DISTINCT user_id
+		FROM user_out_of_office
+		WHERE user_id = ANY($1)
+			AND starts_at <= $2
+			AND ends_at > $2
+	`, pq.Array(userIDs), at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *UserRepository) GetStartedOutOfOffice(
+	ctx context.Context,
+	at time.Time,
+) ([]domain.OutOfOffice, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT out_of_office_id, user_id, starts_at, ends_at
+		FROM user_out_of_office
+		WHERE reassigned_at IS NULL
+			AND starts_at <= $1
+			AND ends_at > $1
+		ORDER BY starts_at, out_of_office_id
+	`, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := make([]domain.OutOfOffice, 0)
+	for rows.Next() {
+		var ooo domain.OutOfOffice
+		if err := rows.Scan(&ooo.ID, &ooo.UserID, &ooo.Start, &ooo.End); err != nil {
+			return nil, err
 		}
+		windows = append(windows, ooo)
 	}
 
-	return tx.Commit()
+	return windows, rows.Err()
+}
+
+func (r *UserRepository) MarkOutOfOfficeReassigned(
+	ctx context.Context,
+	id int64,
+	at time.Time,
+) (bool, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE user_out_of_office
+		SET reassigned_at = $2
+		WHERE out_of_office_id = $1 AND reassigned_at IS NULL
+	`, id, at)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
 }
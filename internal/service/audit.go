@@ -0,0 +1,150 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/ynsssss/pr-manager/internal/domain"
+)
+
+type AuditRepository interface {
+	AddEvents(ctx context.Context, events []domain.AuditEvent) error
+	// ListEvents returns up to filter.Limit events, newest first
+	ListEvents(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, error)
+}
+
+// AuditRecorder appends changes to the audit log. Called with the
+// context of a transaction the event is written in that transaction.
+type AuditRecorder interface {
+	Record(
+		ctx context.Context,
+		eventType domain.AuditEventType,
+		entityType domain.AuditEntityType,
+		entityID string,
+		before, after any,
+	) error
+	// RecordAll records changes of the same kind with one write
+	RecordAll(
+		ctx context.Context,
+		eventType domain.AuditEventType,
+		entityType domain.AuditEntityType,
+		changes []AuditChange,
+	) error
+}
+
+// AuditChange is the state of an entity before and after a change
+type AuditChange struct {
+	EntityID string
+	Before   any
+	After    any
+}
+
+type actorKey struct{}
+
+// WithActor attributes the changes made with ctx to actor
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+type AuditService struct {
+	repo AuditRepository
+}
+
+func NewAuditService(repo AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record stores before and after as JSON, nil values are stored as null.
+// Changes that leave the entity as it was aren't recorded.
+func (s *AuditService) Record(
+	ctx context.Context,
+	eventType domain.AuditEventType,
+	entityType domain.AuditEntityType,
+	entityID string,
+	before, after any,
+) error {
+	return s.RecordAll(ctx, eventType, entityType, []AuditChange{{
+		EntityID: entityID,
+		Before:   before,
+		After:    after,
+	}})
+}
+
+func (s *AuditService) RecordAll(
+	ctx context.Context,
+	eventType domain.AuditEventType,
+	entityType domain.AuditEntityType,
+	changes []AuditChange,
+) error {
+	events := make([]domain.AuditEvent, 0, len(changes))
+	for _, change := range changes {
+		before, err := marshalState(change.Before)
+		if err != nil {
+			return err
+		}
+		after, err := marshalState(change.After)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(before, after) {
+			continue
+		}
+
+		events = append(events, domain.AuditEvent{
+			EventType:  eventType,
+			EntityType: entityType,
+			EntityID:   change.EntityID,
+			Actor:      actorFrom(ctx),
+			Before:     before,
+			After:      after,
+		})
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	return s.repo.AddEvents(ctx, events)
+}
+
+// marshalState returns nil for values that marshal to null,
+// so typed nil pointers don't end up as JSON null in the database
+func marshalState(v any) (json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil || bytes.Equal(data, []byte("null")) {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ListEvents returns a page of audit events matching the filter
+func (s *AuditService) ListEvents(
+	ctx context.Context,
+	filter domain.AuditFilter,
+) (domain.AuditPage, error) {
+	if err := filter.Validate(); err != nil {
+		return domain.AuditPage{}, err
+	}
+
+	events, next, err := listPage(
+		filter.Limit,
+		func(limit int) ([]domain.AuditEvent, error) {
+			filter.Limit = limit
+			return s.repo.ListEvents(ctx, filter)
+		},
+		func(last domain.AuditEvent) domain.Cursor {
+			return domain.Cursor{ID: strconv.FormatInt(last.ID, 10)}
+		},
+	)
+	if err != nil {
+		return domain.AuditPage{}, err
+	}
+
+	return domain.AuditPage{Events: events, NextCursor: next}, nil
+}
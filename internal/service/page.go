@@ -0,0 +1,24 @@
+package service
+
+import "github.com/ynsssss/pr-manager/internal/domain"
+
+// listPage returns up to limit items of a keyset listing. list is asked
+// for one extra item, which tells whether there is a next page. The next
+// cursor continues the listing after the last returned item and is empty
+// on the last page.
+func listPage[T any](
+	limit int,
+	list func(limit int) ([]T, error),
+	cursor func(last T) domain.Cursor,
+) ([]T, string, error) {
+	items, err := list(limit + 1)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(items) <= limit {
+		return items, "", nil
+	}
+
+	items = items[:limit]
+	return items, cursor(items[limit-1]).Encode(), nil
+}
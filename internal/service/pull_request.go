@@ -2,9 +2,9 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"log"
-	"math/rand"
+	"fmt"
 	"slices"
 	"time"
 
@@ -22,154 +22,679 @@ type PullRequestRepository interface {
 	GetByID(ctx context.Context, prID string) (*domain.PullRequest, error)
 
 	GetPullRequestsForUser(ctx context.Context, userID string) ([]domain.PullRequest, error)
+	// CountOpenReviews returns the number of OPEN pull requests assigned
+	// to each of the users, users without any are omitted
+	CountOpenReviews(ctx context.Context, userIDs []string) (map[string]int, error)
+	// ListPullRequests returns up to filter.Limit pull requests, newest first
+	ListPullRequests(ctx context.Context, filter domain.PullRequestFilter) ([]domain.PullRequest, error)
+	// HasOpenPullRequests reports whether any of the users authors an OPEN
+	// or DRAFT pull request or reviews an OPEN one
+	HasOpenPullRequests(ctx context.Context, userIDs []string) (bool, error)
+
+	// GetOpenPullRequestsForReviewers and ReplaceReviewers serve bulk
+	// reassignment, each takes a constant number of queries
+	GetOpenPullRequestsForReviewers(ctx context.Context, userIDs []string) ([]domain.PullRequest, error)
+	ReplaceReviewers(ctx context.Context, reassignments []domain.Reassignment) error
 }
 
 type PullRequestService struct {
-	prRepo   PullRequestRepository
-	userRepo UserRepository
-	teamRepo TeamRepository
+	prRepo    PullRequestRepository
+	userRepo  UserRepository
+	teamRepo  TeamRepository
+	txManager TxManager
+	strategy  ReviewerStrategy
+	audit     AuditRecorder
 }
 
 func NewPullRequestService(
 	prRepo PullRequestRepository,
 	userRepo UserRepository,
 	teamRepo TeamRepository,
+	txManager TxManager,
+	strategy ReviewerStrategy,
+	audit AuditRecorder,
 ) *PullRequestService {
 	return &PullRequestService{
-		prRepo:   prRepo,
-		userRepo: userRepo,
-		teamRepo: teamRepo,
+		prRepo:    prRepo,
+		userRepo:  userRepo,
+		teamRepo:  teamRepo,
+		txManager: txManager,
+		strategy:  strategy,
+		audit:     audit,
 	}
 }
 
+// update runs updateFn on the locked PR and records
+// the change as eventType in the same transaction
+func (s *PullRequestService) update(
+	ctx context.Context,
+	prID string,
+	eventType domain.AuditEventType,
+	updateFn func(ctx context.Context, pr *domain.PullRequest) (*domain.PullRequest, error),
+) (*domain.PullRequest, error) {
+	var updated *domain.PullRequest
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var before json.RawMessage
+		var err error
+		updated, err = s.prRepo.UpdateWithFn(
+			ctx,
+			prID,
+			func(pr *domain.PullRequest) (*domain.PullRequest, error) {
+				// updateFn changes pr in place
+				data, err := json.Marshal(pr)
+				if err != nil {
+					return pr, err
+				}
+				before = data
+				return updateFn(ctx, pr)
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		return s.audit.Record(ctx, eventType, domain.AuditPullRequest, prID, before, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// Create runs in a transaction, so strategies that keep state
+// (like the round-robin cursor) are rolled back with the PR.
+// Draft PRs get their reviewers once they are marked ready.
 func (s *PullRequestService) Create(
 	ctx context.Context,
 	id, title, authorID string,
+	draft bool,
 ) (*domain.PullRequest, error) {
-	pr, err := s.prRepo.GetByID(ctx, id)
-	if err != nil {
-		if !errors.Is(err, domain.ErrNotFound) {
-			return nil, err
+	var newPr *domain.PullRequest
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		pr, err := s.prRepo.GetByID(ctx, id)
+		if err != nil {
+			if !errors.Is(err, domain.ErrNotFound) {
+				return err
+			}
+		}
+		if pr != nil {
+			return domain.ErrPRExists
+		}
+
+		team, err := s.authorTeam(ctx, authorID)
+		if err != nil {
+			return err
 		}
-	}
-	if pr != nil {
-		return nil, domain.ErrPRExists
-	}
 
-	_, err = s.userRepo.GetByID(ctx, authorID)
+		newPrRequest := domain.PullRequest{
+			ID:                id,
+			Name:              title,
+			AuthorID:          authorID,
+			Status:            domain.StatusDraft,
+			AssignedReviewers: []string{},
+		}
+		if !draft {
+			newPrRequest.Status = domain.StatusOpen
+			if err := s.assignOnOpen(ctx, team, &newPrRequest); err != nil {
+				return err
+			}
+		}
+		if err := newPrRequest.Validate(team.TeamSettings); err != nil {
+			return err
+		}
+
+		newPr, err = s.prRepo.Create(ctx, &newPrRequest)
+		if err != nil {
+			return err
+		}
+		newPr.FallbackReviewers = newPrRequest.FallbackReviewers
+		return s.audit.Record(ctx, domain.EventPRCreated, domain.AuditPullRequest, id, nil, newPr)
+	})
 	if err != nil {
-		return nil, domain.ErrNotFound
+		return nil, err
 	}
 
-	reviewers, err := s.pickReviewers(ctx, authorID)
+	return newPr, nil
+}
+
+// authorTeam returns the team of the author. Authors who were removed
+// from their team get the default settings and no reviewer candidates.
+func (s *PullRequestService) authorTeam(ctx context.Context, authorID string) (*domain.Team, error) {
+	author, err := s.userRepo.GetByID(ctx, authorID)
 	if err != nil {
 		return nil, err
 	}
+	if author.TeamName == "" {
+		return &domain.Team{
+			Members:      []domain.TeamMember{},
+			TeamSettings: domain.DefaultTeamSettings(),
+		}, nil
+	}
+	return s.teamRepo.GetTeamByName(ctx, author.TeamName)
+}
 
-	newPrRequest := domain.PullRequest{
-		ID:                id,
-		Name:              title,
-		AuthorID:          authorID,
-		Status:            domain.StatusOpen,
-		AssignedReviewers: reviewers,
+// assignOnOpen assigns reviewers to a PR that becomes OPEN.
+// Under the FAIL capacity policy the PR isn't opened when
+// reviewers at capacity left it with fewer than the team wants.
+func (s *PullRequestService) assignOnOpen(
+	ctx context.Context,
+	team *domain.Team,
+	pr *domain.PullRequest,
+) error {
+	assigned, err := s.assignReviewers(
+		ctx,
+		team,
+		[]string{pr.AuthorID},
+		team.MaxReviewers,
+	)
+	if err != nil {
+		return err
+	}
+	short := len(assigned.reviewers) < team.MaxReviewers
+	if short && assigned.atCapacity && team.CapacityPolicy == domain.CapacityFail {
+		return domain.ErrReviewersAtCapacity
+	}
+	if len(assigned.reviewers) < team.MinReviewers {
+		return domain.ErrNoCandidate
 	}
 
-	newPr, err := s.prRepo.Create(ctx, &newPrRequest)
+	// strategies return nil when there are no candidates
+	pr.AssignedReviewers = append([]string{}, assigned.reviewers...)
+	pr.FallbackReviewers = assigned.fallbackReviewers
+	return nil
+}
+
+// assignment is the outcome of assignReviewers
+type assignment struct {
+	reviewers         []string
+	fallbackReviewers []domain.FallbackReviewer
+	// atCapacity is set when a candidate was skipped
+	// for reviewing too many OPEN pull requests
+	atCapacity bool
+}
+
+// assignReviewers picks up to count reviewers from the team and,
+// when it cannot supply enough, escalates to its ancestor teams
+// and then to its fallback teams in order
+func (s *PullRequestService) assignReviewers(
+	ctx context.Context,
+	team *domain.Team,
+	exclude []string,
+	count int,
+) (assignment, error) {
+	var assigned assignment
+
+	reviewers, atCapacity, err := s.pickReviewers(ctx, team, exclude, count)
 	if err != nil {
-		return nil, err
+		return assignment{}, err
 	}
+	assigned.reviewers = reviewers
+	assigned.atCapacity = atCapacity
 
-	return newPr, nil
+	// pickFrom adds reviewers from another team
+	pickFrom := func(other *domain.Team) error {
+		picked, atCapacity, err := s.pickReviewers(
+			ctx,
+			other,
+			slices.Concat(exclude, assigned.reviewers),
+			count-len(assigned.reviewers),
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range picked {
+			assigned.fallbackReviewers = append(assigned.fallbackReviewers, domain.FallbackReviewer{
+				UserID:   id,
+				TeamName: other.Name,
+			})
+		}
+		assigned.reviewers = append(assigned.reviewers, picked...)
+		assigned.atCapacity = assigned.atCapacity || atCapacity
+		return nil
+	}
+
+	// visited guards against cycles left by concurrent parent updates
+	visited := []string{team.Name}
+	for name := team.ParentTeam; name != "" && len(assigned.reviewers) < count; {
+		if slices.Contains(visited, name) {
+			break
+		}
+		visited = append(visited, name)
+
+		parentTeam, err := s.teamRepo.GetTeamByName(ctx, name)
+		if err != nil {
+			return assignment{}, err
+		}
+		if err := pickFrom(parentTeam); err != nil {
+			return assignment{}, err
+		}
+		name = parentTeam.ParentTeam
+	}
+
+	for _, name := range team.FallbackTeams {
+		if len(assigned.reviewers) >= count {
+			break
+		}
+
+		fallbackTeam, err := s.teamRepo.GetTeamByName(ctx, name)
+		if err != nil {
+			return assignment{}, err
+		}
+		if err := pickFrom(fallbackTeam); err != nil {
+			return assignment{}, err
+		}
+	}
+
+	return assigned, nil
 }
 
+// pickReviewers picks up to count active members of the team
+// using the configured strategy. Excluded users, users who are
+// out of office and users at their review capacity are never picked,
+// the latter are reported.
 func (s *PullRequestService) pickReviewers(
 	ctx context.Context,
-	authorID string,
-) ([]string, error) {
-	team, err := s.teamRepo.GetTeamWithUser(ctx, authorID)
+	team *domain.Team,
+	exclude []string,
+	count int,
+) ([]string, bool, error) {
+	outOfOffice, err := s.userRepo.GetOutOfOfficeUsers(ctx, memberIDs(team.Members), time.Now())
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
+
 	activeMembers := make([]domain.TeamMember, 0, len(team.Members))
 	for _, member := range team.Members {
-		if member.IsActive && member.UserID != authorID {
+		if member.IsActive &&
+			!slices.Contains(exclude, member.UserID) &&
+			!slices.Contains(outOfOffice, member.UserID) {
 			activeMembers = append(activeMembers, member)
 		}
 	}
-	var chosenMembersIDs []string
 
-	members := append([]domain.TeamMember(nil), activeMembers...)
+	candidates, err := s.belowCapacity(ctx, team, activeMembers)
+	if err != nil {
+		return nil, false, err
+	}
 
-	rand.Shuffle(len(members), func(i, j int) {
-		members[i], members[j] = members[j], members[i]
-	})
+	picked, err := s.strategy.Pick(ctx, team, candidates, count)
+	if err != nil {
+		return nil, false, err
+	}
+	return picked, len(candidates) < len(activeMembers), nil
+}
 
-	limit := min(len(members), 2)
-	for i := range limit {
-		chosenMembersIDs = append(chosenMembersIDs, members[i].UserID)
+// belowCapacity returns the members who may take one more review
+func (s *PullRequestService) belowCapacity(
+	ctx context.Context,
+	team *domain.Team,
+	members []domain.TeamMember,
+) ([]domain.TeamMember, error) {
+	var limited []domain.TeamMember
+	for _, member := range members {
+		if team.CapacityOf(member) > 0 {
+			limited = append(limited, member)
+		}
+	}
+	if len(limited) == 0 {
+		return members, nil
 	}
 
-	log.Println(activeMembers, len(activeMembers))
-	log.Println(chosenMembersIDs, len(chosenMembersIDs))
+	load, err := s.prRepo.CountOpenReviews(ctx, memberIDs(limited))
+	if err != nil {
+		return nil, err
+	}
 
-	return chosenMembersIDs, nil
+	candidates := make([]domain.TeamMember, 0, len(members))
+	for _, member := range members {
+		capacity := team.CapacityOf(member)
+		if capacity == 0 || load[member.UserID] < capacity {
+			candidates = append(candidates, member)
+		}
+	}
+	return candidates, nil
 }
 
-// NOTE: it is not a transactional operation yet,
-// consider using transaction manager
+// ReassignReviewer replaces oldReviewer with another active member
+// of the author's team (or its fallback teams). Candidates are
+// computed from the locked PR state, so the author and the reviewers
+// already assigned are never picked.
 func (s *PullRequestService) ReassignReviewer(
 	ctx context.Context,
 	prID, oldReviewer string,
 ) (*domain.PullRequest, string, error) {
-	reviewers, err := s.pickReviewers(ctx, oldReviewer)
-	if err != nil {
-		return nil, "", err
-	}
-	if len(reviewers) == 0 {
-		return nil, "", domain.ErrNoCandidate
-	}
-	newAssignee := reviewers[0]
-	pr, err := s.prRepo.UpdateWithFn(
+	return s.reassignReviewer(ctx, prID, oldReviewer, nil)
+}
+
+// reassignReviewer is ReassignReviewer that never picks any of except
+func (s *PullRequestService) reassignReviewer(
+	ctx context.Context,
+	prID, oldReviewer string,
+	except []string,
+) (*domain.PullRequest, string, error) {
+	var newAssignee string
+	pr, err := s.update(
 		ctx,
 		prID,
-		func(pr *domain.PullRequest) (*domain.PullRequest, error) {
+		domain.EventPRReviewerReassigned,
+		func(ctx context.Context, pr *domain.PullRequest) (*domain.PullRequest, error) {
 			if pr.Status == domain.StatusMerged {
 				return pr, domain.ErrPRMerged
 			}
+			if pr.Status != domain.StatusOpen {
+				return pr, domain.ErrPRNotOpen
+			}
 
 			if !slices.Contains(pr.AssignedReviewers, oldReviewer) {
 				return pr, domain.ErrNotAssigned
 			}
 
+			team, err := s.authorTeam(ctx, pr.AuthorID)
+			if err != nil {
+				return pr, err
+			}
+
+			// the old reviewer is one of the assigned ones
+			exclude := slices.Concat([]string{pr.AuthorID}, pr.AssignedReviewers, except)
+			assigned, err := s.assignReviewers(ctx, team, exclude, 1)
+			if err != nil {
+				return pr, err
+			}
+			if len(assigned.reviewers) == 0 {
+				return pr, domain.ErrNoCandidate
+			}
+			newAssignee = assigned.reviewers[0]
+
 			for i, id := range pr.AssignedReviewers {
 				if id == oldReviewer {
 					pr.AssignedReviewers[i] = newAssignee
 				}
 			}
 
+			pr.FallbackReviewers = assigned.fallbackReviewers
 			return pr, nil
 		},
 	)
-	return pr, newAssignee, err
+	if err != nil {
+		return nil, "", err
+	}
+	return pr, newAssignee, nil
+}
+
+// ReassignAllFor replaces the reviewer on every OPEN PR assigned to them.
+// PRs without a replacement candidate are reported, not failed.
+func (s *PullRequestService) ReassignAllFor(
+	ctx context.Context,
+	reviewerID string,
+) ([]domain.Reassignment, error) {
+	return s.ReassignAllExcept(ctx, reviewerID, nil)
 }
 
-func (s *PullRequestService) Merge(ctx context.Context, prID string) (*domain.PullRequest, error) {
-	return s.prRepo.UpdateWithFn(
+// ReassignAllExcept is ReassignAllFor that never picks any of except
+// as a replacement
+func (s *PullRequestService) ReassignAllExcept(
+	ctx context.Context,
+	reviewerID string,
+	except []string,
+) ([]domain.Reassignment, error) {
+	reassignments := make([]domain.Reassignment, 0)
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		prs, err := s.prRepo.GetPullRequestsForUser(ctx, reviewerID)
+		if err != nil {
+			return err
+		}
+
+		for _, pr := range prs {
+			if pr.Status != domain.StatusOpen {
+				continue
+			}
+
+			reassignment := domain.Reassignment{
+				PullRequestID: pr.ID,
+				OldReviewerID: reviewerID,
+			}
+			_, newReviewerID, err := s.reassignReviewer(ctx, pr.ID, reviewerID, except)
+			switch {
+			case errors.Is(err, domain.ErrNoCandidate):
+				reassignment.NoCandidate = true
+			case err != nil:
+				return err
+			default:
+				reassignment.NewReviewerID = newReviewerID
+			}
+			reassignments = append(reassignments, reassignment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reassignments, nil
+}
+
+// Merge merges the PR once the merge policy of the author's team
+// is satisfied. A non-empty forcedBy bypasses the policy,
+// the user who forced the merge is recorded on the PR.
+func (s *PullRequestService) Merge(
+	ctx context.Context,
+	prID, forcedBy string,
+) (*domain.PullRequest, error) {
+	var merged *domain.PullRequest
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if forcedBy != "" {
+			if _, err := s.userRepo.GetByID(ctx, forcedBy); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		merged, err = s.update(
+			ctx,
+			prID,
+			domain.EventPRMerged,
+			func(ctx context.Context, pr *domain.PullRequest) (*domain.PullRequest, error) {
+				if pr.Status == domain.StatusMerged {
+					return pr, nil
+				}
+
+				if forcedBy == "" && pr.Status.CanTransitionTo(domain.StatusMerged) {
+					team, err := s.authorTeam(ctx, pr.AuthorID)
+					if err != nil {
+						return pr, err
+					}
+					if err := pr.CheckMergePolicy(team.RequiredApprovals); err != nil {
+						return pr, err
+					}
+				}
+
+				if err := pr.TransitionTo(domain.StatusMerged, time.Now()); err != nil {
+					return pr, err
+				}
+				pr.MergeForcedBy = forcedBy
+				return pr, nil
+			},
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
+
+// Close abandons the PR without merging it and releases its reviewers
+func (s *PullRequestService) Close(ctx context.Context, prID string) (*domain.PullRequest, error) {
+	return s.update(
+		ctx,
+		prID,
+		domain.EventPRClosed,
+		func(ctx context.Context, pr *domain.PullRequest) (*domain.PullRequest, error) {
+			return pr, pr.TransitionTo(domain.StatusClosed, time.Now())
+		},
+	)
+}
+
+// MarkReady opens a DRAFT PR and assigns its reviewers
+func (s *PullRequestService) MarkReady(ctx context.Context, prID string) (*domain.PullRequest, error) {
+	return s.open(ctx, prID, domain.StatusDraft, domain.EventPRMarkedReady)
+}
+
+// Reopen opens a CLOSED PR again and assigns new reviewers
+func (s *PullRequestService) Reopen(ctx context.Context, prID string) (*domain.PullRequest, error) {
+	return s.open(ctx, prID, domain.StatusClosed, domain.EventPRReopened)
+}
+
+// open moves a PR from the given status to OPEN and assigns reviewers
+func (s *PullRequestService) open(
+	ctx context.Context,
+	prID string,
+	from domain.PRStatus,
+	eventType domain.AuditEventType,
+) (*domain.PullRequest, error) {
+	return s.update(
+		ctx,
+		prID,
+		eventType,
+		func(ctx context.Context, pr *domain.PullRequest) (*domain.PullRequest, error) {
+			if pr.Status != from {
+				return pr, fmt.Errorf("%w: PR is %s", domain.ErrInvalidTransition, pr.Status)
+			}
+			if err := pr.TransitionTo(domain.StatusOpen, time.Now()); err != nil {
+				return pr, err
+			}
+
+			team, err := s.authorTeam(ctx, pr.AuthorID)
+			if err != nil {
+				return pr, err
+			}
+			if err := s.assignOnOpen(ctx, team, pr); err != nil {
+				return pr, err
+			}
+			return pr, pr.Validate(team.TeamSettings)
+		},
+	)
+}
+
+// Review records the verdict of one of the assigned reviewers
+func (s *PullRequestService) Review(
+	ctx context.Context,
+	prID, reviewerID string,
+	verdict domain.ReviewVerdict,
+) (*domain.PullRequest, error) {
+	if err := verdict.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.update(
 		ctx,
 		prID,
-		func(pr *domain.PullRequest) (*domain.PullRequest, error) {
+		domain.EventPRReviewed,
+		func(ctx context.Context, pr *domain.PullRequest) (*domain.PullRequest, error) {
 			if pr.Status == domain.StatusMerged {
-				return pr, nil
+				return pr, domain.ErrPRMerged
+			}
+			if pr.Status != domain.StatusOpen {
+				return pr, domain.ErrPRNotOpen
+			}
+
+			if !slices.Contains(pr.AssignedReviewers, reviewerID) {
+				return pr, domain.ErrNotAssigned
 			}
 
-			now := time.Now()
-			pr.Status = domain.StatusMerged
-			pr.MergedAt = &now
+			pr.Reviews = append(pr.Reviews, domain.Review{
+				ReviewerID: reviewerID,
+				Verdict:    verdict,
+				CreatedAt:  time.Now(),
+			})
 			return pr, nil
 		},
 	)
 }
 
+// GetReviewLoad compares the OPEN pull requests the user reviews
+// with the capacity from their own limit or their team's default
+func (s *PullRequestService) GetReviewLoad(
+	ctx context.Context,
+	userID string,
+) (domain.ReviewLoad, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return domain.ReviewLoad{}, err
+	}
+
+	// users without a team have no team default
+	team := &domain.Team{}
+	if user.TeamName != "" {
+		team, err = s.teamRepo.GetTeamByName(ctx, user.TeamName)
+		if err != nil {
+			return domain.ReviewLoad{}, err
+		}
+	}
+
+	load, err := s.prRepo.CountOpenReviews(ctx, []string{userID})
+	if err != nil {
+		return domain.ReviewLoad{}, err
+	}
+
+	return domain.ReviewLoad{
+		OpenReviews:    load[userID],
+		MaxOpenReviews: team.CapacityOf(domain.TeamMember{MaxOpenReviews: user.MaxOpenReviews}),
+	}, nil
+}
+
+// GetDetails returns the pull request with the usernames
+// and latest verdicts of its reviewers
+func (s *PullRequestService) GetDetails(
+	ctx context.Context,
+	prID string,
+) (domain.PullRequestDetails, error) {
+	pr, err := s.prRepo.GetByID(ctx, prID)
+	if err != nil {
+		return domain.PullRequestDetails{}, err
+	}
+
+	users, err := s.userRepo.GetByIDs(ctx, pr.AssignedReviewers)
+	if err != nil {
+		return domain.PullRequestDetails{}, err
+	}
+
+	usernames := make(map[string]string, len(users))
+	for _, u := range users {
+		usernames[u.ID] = u.Username
+	}
+	return domain.NewPullRequestDetails(*pr, usernames), nil
+}
+
+// ListPullRequests returns a page of pull requests matching the filter
+func (s *PullRequestService) ListPullRequests(
+	ctx context.Context,
+	filter domain.PullRequestFilter,
+) (domain.PullRequestPage, error) {
+	if err := filter.Validate(); err != nil {
+		return domain.PullRequestPage{}, err
+	}
+
+	prs, next, err := listPage(
+		filter.Limit,
+		func(limit int) ([]domain.PullRequest, error) {
+			filter.Limit = limit
+			return s.prRepo.ListPullRequests(ctx, filter)
+		},
+		func(last domain.PullRequest) domain.Cursor {
+			return domain.Cursor{Time: last.CreatedAt, ID: last.ID}
+		},
+	)
+	if err != nil {
+		return domain.PullRequestPage{}, err
+	}
+
+	return domain.PullRequestPage{PullRequests: prs, NextCursor: next}, nil
+}
+
 func (s *PullRequestService) GetPullRequestsForUser(ctx context.Context, userId string) (
 	[]domain.PullRequest,
 	error,
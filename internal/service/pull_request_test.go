@@ -0,0 +1,180 @@
+package service_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ynsssss/pr-manager/internal/domain"
+	"github.com/ynsssss/pr-manager/internal/repository/memory"
+	"github.com/ynsssss/pr-manager/internal/service"
+)
+
+type services struct {
+	team   *service.TeamService
+	pr     *service.PullRequestService
+	prRepo service.PullRequestRepository
+}
+
+func newServices() services {
+	db := memory.NewDB()
+	userRepo := memory.NewUserRepository(db)
+	teamRepo := memory.NewTeamRepository(db)
+	prRepo := memory.NewPullRequestRepository(db)
+	txManager := memory.NewTxManager(db)
+	audit := service.NewAuditService(memory.NewAuditRepository(db))
+
+	prService := service.NewPullRequestService(
+		prRepo, userRepo, teamRepo, txManager, service.NewRandomStrategy(), audit,
+	)
+	return services{
+		team:   service.NewTeamService(teamRepo, userRepo, prRepo, txManager, prService, audit),
+		pr:     prService,
+		prRepo: prRepo,
+	}
+}
+
+// addTeam creates a team with the default settings,
+// members listed in inactive are created inactive
+func (s services) addTeam(t *testing.T, name string, members []string, inactive ...string) {
+	t.Helper()
+
+	team := &domain.Team{Name: name, TeamSettings: domain.DefaultTeamSettings()}
+	for _, id := range members {
+		team.Members = append(team.Members, domain.TeamMember{
+			UserID:   id,
+			Username: id,
+			IsActive: !slices.Contains(inactive, id),
+		})
+	}
+	if _, err := s.team.AddTeam(t.Context(), team); err != nil {
+		t.Fatalf("add team %q: %v", name, err)
+	}
+}
+
+func (s services) createPR(t *testing.T, id, authorID string, draft bool) *domain.PullRequest {
+	t.Helper()
+
+	pr, err := s.pr.Create(t.Context(), id, id, authorID, draft)
+	if err != nil {
+		t.Fatalf("create PR %q: %v", id, err)
+	}
+	return pr
+}
+
+func TestReassignReviewerNeverPicksExcludedUsers(t *testing.T) {
+	s := newServices()
+	s.addTeam(t, "backend", []string{"author", "u1", "u2", "u3", "u4", "u5"})
+	pr := s.createPR(t, "pr-1", "author", false)
+
+	// the random strategy needs a few rounds to hit every candidate
+	for range 50 {
+		oldReviewer := pr.AssignedReviewers[0]
+		others := slices.Clone(pr.AssignedReviewers[1:])
+
+		updated, newReviewer, err := s.pr.ReassignReviewer(t.Context(), pr.ID, oldReviewer)
+		if err != nil {
+			t.Fatalf("reassign %q: %v", oldReviewer, err)
+		}
+
+		switch {
+		case newReviewer == "author":
+			t.Fatalf("author was picked as reviewer")
+		case newReviewer == oldReviewer:
+			t.Fatalf("outgoing reviewer %q was picked again", oldReviewer)
+		case slices.Contains(others, newReviewer):
+			t.Fatalf("already assigned reviewer %q was picked", newReviewer)
+		}
+		if want := append([]string{newReviewer}, others...); !slices.Equal(updated.AssignedReviewers, want) {
+			t.Fatalf("assigned reviewers = %v, want %v", updated.AssignedReviewers, want)
+		}
+
+		pr = updated
+	}
+}
+
+func TestReassignReviewerWithoutCandidates(t *testing.T) {
+	s := newServices()
+	// inactive is the only member besides the author and the reviewers
+	s.addTeam(t, "backend", []string{"author", "u1", "u2", "inactive"}, "inactive")
+	pr := s.createPR(t, "pr-1", "author", false)
+	if len(pr.AssignedReviewers) != 2 {
+		t.Fatalf("assigned reviewers = %v, want u1 and u2", pr.AssignedReviewers)
+	}
+
+	_, _, err := s.pr.ReassignReviewer(t.Context(), pr.ID, pr.AssignedReviewers[0])
+	if !errors.Is(err, domain.ErrNoCandidate) {
+		t.Fatalf("err = %v, want %v", err, domain.ErrNoCandidate)
+	}
+
+	unchanged, err := s.prRepo.GetByID(t.Context(), pr.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(unchanged.AssignedReviewers, pr.AssignedReviewers) {
+		t.Fatalf("failed reassignment changed the reviewers: %v", unchanged.AssignedReviewers)
+	}
+}
+
+func TestReassignReviewerRejectsPRsThatAreNotOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, s services) string
+		wantErr error
+	}{
+		{
+			name: "merged",
+			prepare: func(t *testing.T, s services) string {
+				pr := s.createPR(t, "pr-1", "author", false)
+				if _, err := s.pr.Merge(t.Context(), pr.ID, ""); err != nil {
+					t.Fatal(err)
+				}
+				return pr.AssignedReviewers[0]
+			},
+			wantErr: domain.ErrPRMerged,
+		},
+		{
+			name: "closed",
+			prepare: func(t *testing.T, s services) string {
+				pr := s.createPR(t, "pr-1", "author", false)
+				if _, err := s.pr.Close(t.Context(), pr.ID); err != nil {
+					t.Fatal(err)
+				}
+				return pr.AssignedReviewers[0]
+			},
+			wantErr: domain.ErrPRNotOpen,
+		},
+		{
+			name: "draft",
+			prepare: func(t *testing.T, s services) string {
+				s.createPR(t, "pr-1", "author", true)
+				return "u1"
+			},
+			wantErr: domain.ErrPRNotOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServices()
+			s.addTeam(t, "backend", []string{"author", "u1", "u2", "u3"})
+			reviewer := tt.prepare(t, s)
+
+			_, _, err := s.pr.ReassignReviewer(t.Context(), "pr-1", reviewer)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReassignReviewerRejectsUnassignedReviewer(t *testing.T) {
+	s := newServices()
+	s.addTeam(t, "backend", []string{"author", "u1", "u2", "u3"})
+	pr := s.createPR(t, "pr-1", "author", false)
+
+	_, _, err := s.pr.ReassignReviewer(t.Context(), pr.ID, "author")
+	if !errors.Is(err, domain.ErrNotAssigned) {
+		t.Fatalf("err = %v, want %v", err, domain.ErrNotAssigned)
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"strings"
+
+	"github.com/ynsssss/pr-manager/internal/domain"
+)
+
+// ReviewerStrategy chooses up to count reviewers out of candidates.
+// Candidates are already filtered by the service: they are active
+// members of team and never include the author.
+type ReviewerStrategy interface {
+	Pick(
+		ctx context.Context,
+		team *domain.Team,
+		candidates []domain.TeamMember,
+		count int,
+	) ([]string, error)
+}
+
+// RandomStrategy picks candidates uniformly at random
+type RandomStrategy struct{}
+
+func NewRandomStrategy() *RandomStrategy {
+	return &RandomStrategy{}
+}
+
+func (RandomStrategy) Pick(
+	ctx context.Context,
+	team *domain.Team,
+	candidates []domain.TeamMember,
+	count int,
+) ([]string, error) {
+	members := shuffled(candidates)
+
+	return memberIDs(members[:min(len(members), count)]), nil
+}
+
+// LeastLoadedStrategy prefers candidates with the fewest OPEN pull
+// requests assigned to them, ties are broken randomly
+type LeastLoadedStrategy struct {
+	prRepo PullRequestRepository
+}
+
+func NewLeastLoadedStrategy(prRepo PullRequestRepository) *LeastLoadedStrategy {
+	return &LeastLoadedStrategy{prRepo: prRepo}
+}
+
+func (s *LeastLoadedStrategy) Pick(
+	ctx context.Context,
+	team *domain.Team,
+	candidates []domain.TeamMember,
+	count int,
+) ([]string, error) {
+	if len(candidates) == 0 || count <= 0 {
+		return nil, nil
+	}
+
+	load, err := s.prRepo.CountOpenReviews(ctx, memberIDs(candidates))
+	if err != nil {
+		return nil, err
+	}
+
+	// shuffling before a stable sort breaks the ties randomly
+	members := shuffled(candidates)
+	slices.SortStableFunc(members, func(a, b domain.TeamMember) int {
+		return load[a.UserID] - load[b.UserID]
+	})
+
+	return memberIDs(members[:min(len(members), count)]), nil
+}
+
+type RotationRepository interface {
+	// UpdateCursorWithFn passes the last user picked in the team to
+	// updateFn and stores the returned one. Concurrent calls for the
+	// same team are serialised.
+	UpdateCursorWithFn(
+		ctx context.Context,
+		teamName string,
+		updateFn func(lastUserID string) (string, error),
+	) error
+}
+
+// RoundRobinStrategy walks the team members ordered by user ID,
+// starting after the last picked one. The cursor is persisted
+// per team, so the rotation survives restarts.
+type RoundRobinStrategy struct {
+	rotationRepo RotationRepository
+}
+
+func NewRoundRobinStrategy(rotationRepo RotationRepository) *RoundRobinStrategy {
+	return &RoundRobinStrategy{rotationRepo: rotationRepo}
+}
+
+func (s *RoundRobinStrategy) Pick(
+	ctx context.Context,
+	team *domain.Team,
+	candidates []domain.TeamMember,
+	count int,
+) ([]string, error) {
+	if len(candidates) == 0 || count <= 0 {
+		return nil, nil
+	}
+
+	members := append([]domain.TeamMember(nil), candidates...)
+	slices.SortFunc(members, func(a, b domain.TeamMember) int {
+		return strings.Compare(a.UserID, b.UserID)
+	})
+
+	var picked []string
+	err := s.rotationRepo.UpdateCursorWithFn(
+		ctx,
+		team.Name,
+		func(lastUserID string) (string, error) {
+			picked = nil
+			// the last picked user may be inactive or gone by now,
+			// so start from the first member ordered after it
+			start, _ := slices.BinarySearchFunc(
+				members,
+				lastUserID,
+				func(m domain.TeamMember, id string) int {
+					return strings.Compare(m.UserID, id)
+				},
+			)
+			if start < len(members) && members[start].UserID == lastUserID {
+				start++
+			}
+
+			for i := range min(len(members), count) {
+				picked = append(picked, members[(start+i)%len(members)].UserID)
+			}
+			return picked[len(picked)-1], nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return picked, nil
+}
+
+func shuffled(members []domain.TeamMember) []domain.TeamMember {
+	members = append([]domain.TeamMember(nil), members...)
+	rand.Shuffle(len(members), func(i, j int) {
+		members[i], members[j] = members[j], members[i]
+	})
+	return members
+}
+
+func memberIDs(members []domain.TeamMember) []string {
+	var ids []string
+	for _, member := range members {
+		ids = append(ids, member.UserID)
+	}
+	return ids
+}
@@ -2,6 +2,11 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/ynsssss/pr-manager/internal/domain"
 )
@@ -12,65 +17,873 @@ type TeamRepository interface {
 
 	GetTeamByName(ctx context.Context, teamName string) (*domain.Team, error)
 	GetTeamWithUser(ctx context.Context, userID string) (*domain.Team, error)
+	// GetTeamWithSubteams adds the members of all sub-teams to the team
+	GetTeamWithSubteams(ctx context.Context, teamName string) (*domain.Team, error)
+
+	UpdateSettingsWithFn(
+		ctx context.Context,
+		teamName string,
+		updateFn func(settings *domain.TeamSettings) error,
+	) error
+
+	// DeleteTeam leaves the members of the team without a team
+	DeleteTeam(ctx context.Context, teamName string) error
+	RenameTeam(ctx context.Context, oldName, newName string) error
+	// SetParentTeam makes the team top-level when parentTeam is empty
+	SetParentTeam(ctx context.Context, teamName, parentTeam string) error
+	// LockAncestors locks the team and its ancestors until the transaction
+	// ends and returns their names, the team first. A cycle among them
+	// is reported as ErrTeamCycle.
+	LockAncestors(ctx context.Context, teamName string) ([]string, error)
+
+	// ListTeamStats returns the stats of every team ordered by name
+	ListTeamStats(ctx context.Context) ([]domain.TeamStats, error)
 }
 
 type TeamService struct {
-	teamRepo TeamRepository
-	userRepo UserRepository
+	teamRepo   TeamRepository
+	userRepo   UserRepository
+	prRepo     PullRequestRepository
+	txManager  TxManager
+	reassigner ReviewReassigner
+	audit      AuditRecorder
 }
 
-func NewTeamService(teamRepo TeamRepository, userRepo UserRepository) *TeamService {
+func NewTeamService(
+	teamRepo TeamRepository,
+	userRepo UserRepository,
+	prRepo PullRequestRepository,
+	txManager TxManager,
+	reassigner ReviewReassigner,
+	audit AuditRecorder,
+) *TeamService {
 	return &TeamService{
-		teamRepo: teamRepo,
-		userRepo: userRepo,
+		teamRepo:   teamRepo,
+		userRepo:   userRepo,
+		prRepo:     prRepo,
+		txManager:  txManager,
+		reassigner: reassigner,
+		audit:      audit,
 	}
 }
 
-// NOTE: operations here could be used in a single db transaction
-// but given the RPS and the use case, it doesn't seem right but
-// I could make them a transaction in repository implementation
-// if that would be needed
+// AddTeam creates the team and its members in a single transaction,
+// so a failed members upsert doesn't leave a half-created team
 func (s *TeamService) AddTeam(ctx context.Context, team *domain.Team) (*domain.Team, error) {
 	if err := team.Validate(); err != nil {
 		return nil, err
 	}
-	// Or GetTeam and then check if it's nil
-	exists, err := s.teamRepo.TeamExists(ctx, team.Name)
+
+	var newTeam *domain.Team
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// Or GetTeam and then check if it's nil
+		exists, err := s.teamRepo.TeamExists(ctx, team.Name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return domain.ErrTeamExists
+		}
+
+		if err := s.checkFallbackTeams(ctx, team.FallbackTeams); err != nil {
+			return err
+		}
+		if err := s.checkParentTeam(ctx, team.Name, team.ParentTeam); err != nil {
+			return err
+		}
+
+		newTeam, err = s.teamRepo.CreateTeam(ctx, team)
+		if err != nil {
+			return err
+		}
+
+		users := make([]domain.User, 0, len(team.Members))
+		for _, member := range team.Members {
+			users = append(users, domain.User{
+				ID:             member.UserID,
+				Username:       member.Username,
+				TeamName:       team.Name,
+				IsActive:       member.IsActive,
+				MaxOpenReviews: member.MaxOpenReviews,
+			})
+		}
+
+		if err := s.userRepo.UpsertUsers(ctx, users); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, domain.EventTeamCreated, domain.AuditTeam, team.Name, nil, newTeam)
+	})
 	if err != nil {
 		return nil, err
 	}
-	if exists {
-		return nil, domain.ErrTeamExists
-	}
 
-	newTeam, err := s.teamRepo.CreateTeam(ctx, team)
+	return newTeam, nil
+}
+
+// GetWithSubteams returns the team with the members
+// of its sub-teams at any depth
+func (s *TeamService) GetWithSubteams(ctx context.Context, teamName string) (*domain.Team, error) {
+	return s.teamRepo.GetTeamWithSubteams(ctx, teamName)
+}
+
+// SetParent makes the team a sub-team of parentTeam,
+// an empty parentTeam makes it a top-level team
+func (s *TeamService) SetParent(
+	ctx context.Context,
+	teamName, parentTeam string,
+) (*domain.Team, error) {
+	var team *domain.Team
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.teamRepo.GetTeamByName(ctx, teamName)
+		if err != nil {
+			return err
+		}
+		if err := s.checkParentTeam(ctx, teamName, parentTeam); err != nil {
+			return err
+		}
+		if err := s.teamRepo.SetParentTeam(ctx, teamName, parentTeam); err != nil {
+			return err
+		}
+
+		team, err = s.teamRepo.GetTeamByName(ctx, teamName)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, domain.EventTeamParentChanged, domain.AuditTeam, teamName, before, team)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	users := make([]domain.User, 0, len(team.Members))
-	for _, member := range team.Members {
-		users = append(users, domain.User{
-			ID:       member.UserID,
-			Username: member.Username,
-			TeamName: team.Name,
-			IsActive: member.IsActive,
-		})
+	return team, nil
+}
+
+func (s *TeamService) GetByName(ctx context.Context, teamName string) (*domain.Team, error) {
+	team, err := s.teamRepo.GetTeamByName(ctx, teamName)
+	if err != nil {
+		return nil, err
 	}
 
-	err = s.userRepo.UpsertUsers(ctx, users)
+	return team, nil
+}
+
+// ListTeams returns every team with its member and review stats
+func (s *TeamService) ListTeams(ctx context.Context) ([]domain.TeamStats, error) {
+	return s.teamRepo.ListTeamStats(ctx)
+}
+
+// UpdateSettings applies the changes made by updateFn
+// to the team settings if the result is valid
+func (s *TeamService) UpdateSettings(
+	ctx context.Context,
+	teamName string,
+	updateFn func(settings *domain.TeamSettings),
+) (*domain.Team, error) {
+	var team *domain.Team
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.teamRepo.GetTeamByName(ctx, teamName)
+		if err != nil {
+			return err
+		}
+
+		err = s.teamRepo.UpdateSettingsWithFn(
+			ctx,
+			teamName,
+			func(settings *domain.TeamSettings) error {
+				updateFn(settings)
+
+				team := domain.Team{Name: teamName, TeamSettings: *settings}
+				if err := team.Validate(); err != nil {
+					return err
+				}
+				return s.checkFallbackTeams(ctx, settings.FallbackTeams)
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		team, err = s.teamRepo.GetTeamByName(ctx, teamName)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, domain.EventTeamSettingsUpdated, domain.AuditTeam, teamName, before, team)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return newTeam, nil
+	return team, nil
 }
 
-func (s *TeamService) GetByName(ctx context.Context, teamName string) (*domain.Team, error) {
-	team, err := s.teamRepo.GetTeamByName(ctx, teamName)
+// checkParentTeam makes sure the parent exists and
+// doesn't have the team among its ancestors. The ancestors stay
+// locked, so concurrent parent changes can't close a cycle
+// that neither of them sees.
+func (s *TeamService) checkParentTeam(ctx context.Context, teamName, parentTeam string) error {
+	if parentTeam == "" {
+		return nil
+	}
+
+	ancestors, err := s.teamRepo.LockAncestors(ctx, parentTeam)
+	if err != nil {
+		return fmt.Errorf("parent team %q: %w", parentTeam, err)
+	}
+	if slices.Contains(ancestors, teamName) {
+		return domain.ErrTeamCycle
+	}
+	return nil
+}
+
+func (s *TeamService) checkFallbackTeams(ctx context.Context, names []string) error {
+	for _, name := range names {
+		exists, err := s.teamRepo.TeamExists(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("fallback team %q: %w", name, domain.ErrNotFound)
+		}
+	}
+	return nil
+}
+
+// DeactivateUsers deactivates the given members of the team and moves
+// their open reviews to the remaining active members in one transaction.
+// The number of queries doesn't depend on the number of users or PRs:
+// replacements are balanced by load in memory rather than going
+// through the ReviewerStrategy one PR at a time.
+func (s *TeamService) DeactivateUsers(
+	ctx context.Context,
+	teamName string,
+	userIDs []string,
+) ([]domain.User, []domain.Reassignment, error) {
+	if len(userIDs) == 0 {
+		return nil, nil, domain.ErrNoUserIDs
+	}
+
+	var (
+		users         []domain.User
+		reassignments []domain.Reassignment
+	)
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		team, err := s.teamRepo.GetTeamByName(ctx, teamName)
+		if err != nil {
+			return err
+		}
+
+		members := make(map[string]domain.TeamMember, len(team.Members))
+		for _, member := range team.Members {
+			members[member.UserID] = member
+		}
+		for _, id := range userIDs {
+			if _, ok := members[id]; !ok {
+				return fmt.Errorf("user %q in team %q: %w", id, teamName, domain.ErrNotFound)
+			}
+		}
+
+		users, err = s.userRepo.SetIsActiveForUsers(ctx, userIDs, false)
+		if err != nil {
+			return err
+		}
+
+		outOfOffice, err := s.userRepo.GetOutOfOfficeUsers(ctx, memberIDs(team.Members), time.Now())
+		if err != nil {
+			return err
+		}
+
+		var candidates []string
+		capacity := make(map[string]int)
+		for _, member := range shuffled(team.Members) {
+			if member.IsActive &&
+				!slices.Contains(userIDs, member.UserID) &&
+				!slices.Contains(outOfOffice, member.UserID) {
+				candidates = append(candidates, member.UserID)
+				capacity[member.UserID] = team.CapacityOf(member)
+			}
+		}
+
+		load, err := s.prRepo.CountOpenReviews(ctx, candidates)
+		if err != nil {
+			return err
+		}
+
+		prs, err := s.prRepo.GetOpenPullRequestsForReviewers(ctx, userIDs)
+		if err != nil {
+			return err
+		}
+
+		reassignments = make([]domain.Reassignment, 0, len(prs))
+		changes := make([]AuditChange, 0, len(prs))
+		for _, pr := range prs {
+			// pr is changed in place below
+			before, err := json.Marshal(pr)
+			if err != nil {
+				return err
+			}
+
+			for i, reviewerID := range pr.AssignedReviewers {
+				if !slices.Contains(userIDs, reviewerID) {
+					continue
+				}
+
+				reassignment := domain.Reassignment{
+					PullRequestID: pr.ID,
+					OldReviewerID: reviewerID,
+				}
+				exclude := append([]string{pr.AuthorID}, pr.AssignedReviewers...)
+				newReviewerID, ok := leastLoaded(candidates, load, capacity, exclude)
+				if ok {
+					reassignment.NewReviewerID = newReviewerID
+					pr.AssignedReviewers[i] = newReviewerID
+					load[newReviewerID]++
+				} else {
+					reassignment.NoCandidate = true
+				}
+				reassignments = append(reassignments, reassignment)
+			}
+			changes = append(changes, AuditChange{
+				EntityID: pr.ID,
+				Before:   json.RawMessage(before),
+				After:    pr,
+			})
+		}
+
+		if err := s.prRepo.ReplaceReviewers(ctx, reassignments); err != nil {
+			return err
+		}
+		err = s.audit.RecordAll(ctx, domain.EventPRReviewerReassigned, domain.AuditPullRequest, changes)
+		if err != nil {
+			return err
+		}
+
+		after, err := s.teamRepo.GetTeamByName(ctx, teamName)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, domain.EventTeamUsersDeactivated, domain.AuditTeam, teamName, team, after)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return users, reassignments, nil
+}
+
+// AddMembers adds the members to the team, creating the users
+// that don't exist yet. Members who get deactivated always have their
+// open reviews reassigned, members who belonged to another team
+// according to policy.
+func (s *TeamService) AddMembers(
+	ctx context.Context,
+	teamName string,
+	members []domain.TeamMember,
+	policy domain.ReviewPolicy,
+) (*domain.Team, []domain.Reassignment, error) {
+	if len(members) == 0 {
+		return nil, nil, domain.ErrNoUserIDs
+	}
+	for _, member := range members {
+		if err := member.Validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		team          *domain.Team
+		reassignments []domain.Reassignment
+	)
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.teamRepo.GetTeamByName(ctx, teamName)
+		if err != nil {
+			return err
+		}
+
+		var moved, deactivated []string
+		users := make([]domain.User, 0, len(members))
+		for _, member := range members {
+			user, err := s.userRepo.GetByID(ctx, member.UserID)
+			switch {
+			case errors.Is(err, domain.ErrNotFound):
+			case err != nil:
+				return err
+			case user.IsActive && !member.IsActive:
+				// reviews of deactivated members are reassigned
+				// whatever the policy, so only once
+				deactivated = append(deactivated, member.UserID)
+			case user.TeamName != "" && user.TeamName != teamName:
+				moved = append(moved, member.UserID)
+			}
+
+			users = append(users, domain.User{
+				ID:             member.UserID,
+				Username:       member.Username,
+				TeamName:       teamName,
+				IsActive:       member.IsActive,
+				MaxOpenReviews: member.MaxOpenReviews,
+			})
+		}
+
+		if err := s.userRepo.UpsertUsers(ctx, users); err != nil {
+			return err
+		}
+
+		reassignments = make([]domain.Reassignment, 0)
+		for _, id := range deactivated {
+			deactivatedReassignments, err := s.reassigner.ReassignAllFor(ctx, id)
+			if err != nil {
+				return err
+			}
+			reassignments = append(reassignments, deactivatedReassignments...)
+		}
+
+		movedReassignments, err := s.applyReviewPolicy(ctx, moved, policy, nil)
+		if err != nil {
+			return err
+		}
+		reassignments = append(reassignments, movedReassignments...)
+
+		team, err = s.teamRepo.GetTeamByName(ctx, teamName)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, domain.EventTeamMembersAdded, domain.AuditTeam, teamName, before, team)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return team, reassignments, nil
+}
+
+// RemoveMembers detaches the members from the team, the users
+// are kept without a team. Their open reviews are handled
+// according to policy.
+func (s *TeamService) RemoveMembers(
+	ctx context.Context,
+	teamName string,
+	userIDs []string,
+	policy domain.ReviewPolicy,
+) (*domain.Team, []domain.Reassignment, error) {
+	if len(userIDs) == 0 {
+		return nil, nil, domain.ErrNoUserIDs
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		team          *domain.Team
+		reassignments []domain.Reassignment
+	)
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.teamRepo.GetTeamByName(ctx, teamName)
+		if err != nil {
+			return err
+		}
+
+		members := memberIDs(before.Members)
+		for _, id := range userIDs {
+			if !slices.Contains(members, id) {
+				return fmt.Errorf("user %q in team %q: %w", id, teamName, domain.ErrNotFound)
+			}
+		}
+
+		if _, err := s.userRepo.SetTeamForUsers(ctx, userIDs, ""); err != nil {
+			return err
+		}
+
+		reassignments, err = s.applyReviewPolicy(ctx, userIDs, policy, nil)
+		if err != nil {
+			return err
+		}
+
+		team, err = s.teamRepo.GetTeamByName(ctx, teamName)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, domain.EventTeamMembersRemoved, domain.AuditTeam, teamName, before, team)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return team, reassignments, nil
+}
+
+// MoveUser moves the user to another team, their open reviews
+// are handled according to policy
+func (s *TeamService) MoveUser(
+	ctx context.Context,
+	userID string,
+	teamName string,
+	policy domain.ReviewPolicy,
+) (domain.User, []domain.Reassignment, error) {
+	if err := policy.Validate(); err != nil {
+		return domain.User{}, nil, err
+	}
+
+	var (
+		user          domain.User
+		reassignments = make([]domain.Reassignment, 0)
+	)
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.teamRepo.GetTeamByName(ctx, teamName); err != nil {
+			return err
+		}
+
+		before, err := s.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		user = before
+		if user.TeamName == teamName {
+			return nil
+		}
+
+		users, err := s.userRepo.SetTeamForUsers(ctx, []string{userID}, teamName)
+		if err != nil {
+			return err
+		}
+		user = users[0]
+
+		reassignments, err = s.applyReviewPolicy(ctx, []string{userID}, policy, nil)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, domain.EventUserTeamChanged, domain.AuditUser, userID, before, user)
+	})
+	if err != nil {
+		return domain.User{}, nil, err
+	}
+
+	return user, reassignments, nil
+}
+
+// DeleteTeam deletes the team and detaches its members. Unless forced
+// it refuses to when members author or review open pull requests,
+// forced deletion handles their open reviews according to policy.
+// Replacements come from outside the team: the author's team, or for
+// authors in the deleted team its ancestors and fallback teams.
+// Other teams stop using the team as a fallback.
+func (s *TeamService) DeleteTeam(
+	ctx context.Context,
+	teamName string,
+	force bool,
+	policy domain.ReviewPolicy,
+) ([]domain.User, []domain.Reassignment, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		users         []domain.User
+		reassignments []domain.Reassignment
+	)
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		team, err := s.teamRepo.GetTeamByName(ctx, teamName)
+		if err != nil {
+			return err
+		}
+		userIDs := memberIDs(team.Members)
+
+		if !force {
+			hasOpen, err := s.prRepo.HasOpenPullRequests(ctx, userIDs)
+			if err != nil {
+				return err
+			}
+			if hasOpen {
+				return domain.ErrTeamHasOpenPRs
+			}
+		}
+
+		// reviews are moved while the authors still have their team,
+		// so it can escalate to its parent and fallback teams
+		reassignments, err = s.applyReviewPolicy(ctx, userIDs, policy, userIDs)
+		if err != nil {
+			return err
+		}
+
+		users, err = s.userRepo.SetTeamForUsers(ctx, userIDs, "")
+		if err != nil {
+			return err
+		}
+
+		if err := s.teamRepo.DeleteTeam(ctx, teamName); err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, domain.EventTeamDeleted, domain.AuditTeam, teamName, team, nil)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return users, reassignments, nil
+}
+
+// RenameTeam renames the team, members, settings and
+// fallbacks of other teams follow it
+func (s *TeamService) RenameTeam(
+	ctx context.Context,
+	oldName, newName string,
+) (*domain.Team, error) {
+	if newName == "" {
+		return nil, domain.ErrEmptyTeamName
+	}
+
+	var team *domain.Team
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		exists, err := s.teamRepo.TeamExists(ctx, newName)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return domain.ErrTeamExists
+		}
+
+		before, err := s.teamRepo.GetTeamByName(ctx, oldName)
+		if err != nil {
+			return err
+		}
+		if err := s.teamRepo.RenameTeam(ctx, oldName, newName); err != nil {
+			return err
+		}
+
+		team, err = s.teamRepo.GetTeamByName(ctx, newName)
+		if err != nil {
+			return err
+		}
+		// filed under both names, so the history of either leads to it
+		return s.audit.RecordAll(ctx, domain.EventTeamRenamed, domain.AuditTeam, []AuditChange{
+			{EntityID: oldName, Before: before, After: team},
+			{EntityID: newName, Before: before, After: team},
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return team, nil
 }
+
+// SyncTeam makes the stored team match the given one: the team is
+// created when missing, settings are replaced and the listed members
+// upserted. Unlisted members are deactivated or removed, members who
+// get deactivated always have their open reviews reassigned, removed
+// and moved in members according to policy.
+func (s *TeamService) SyncTeam(
+	ctx context.Context,
+	team *domain.Team,
+	unlisted domain.UnlistedPolicy,
+	policy domain.ReviewPolicy,
+) (*domain.Team, domain.TeamDiff, error) {
+	if err := team.Validate(); err != nil {
+		return nil, domain.TeamDiff{}, err
+	}
+	if err := unlisted.Validate(); err != nil {
+		return nil, domain.TeamDiff{}, err
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, domain.TeamDiff{}, err
+	}
+
+	var synced *domain.Team
+	diff := domain.TeamDiff{
+		Added:         make([]string, 0),
+		Updated:       make([]string, 0),
+		Deactivated:   make([]string, 0),
+		Removed:       make([]string, 0),
+		Reassignments: make([]domain.Reassignment, 0),
+	}
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		current, err := s.teamRepo.GetTeamByName(ctx, team.Name)
+		before := current
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			if err := s.checkFallbackTeams(ctx, team.FallbackTeams); err != nil {
+				return err
+			}
+			if err := s.checkParentTeam(ctx, team.Name, team.ParentTeam); err != nil {
+				return err
+			}
+			if _, err := s.teamRepo.CreateTeam(ctx, team); err != nil {
+				return err
+			}
+			current = &domain.Team{Name: team.Name, Members: []domain.TeamMember{}}
+			diff.Created = true
+		case err != nil:
+			return err
+		}
+
+		if !diff.Created && current.ParentTeam != team.ParentTeam {
+			if err := s.checkParentTeam(ctx, team.Name, team.ParentTeam); err != nil {
+				return err
+			}
+			if err := s.teamRepo.SetParentTeam(ctx, team.Name, team.ParentTeam); err != nil {
+				return err
+			}
+			diff.ParentChanged = true
+		}
+
+		if !diff.Created && !current.TeamSettings.Equal(team.TeamSettings) {
+			if err := s.checkFallbackTeams(ctx, team.FallbackTeams); err != nil {
+				return err
+			}
+			err := s.teamRepo.UpdateSettingsWithFn(
+				ctx,
+				team.Name,
+				func(settings *domain.TeamSettings) error {
+					*settings = team.TeamSettings
+					return nil
+				},
+			)
+			if err != nil {
+				return err
+			}
+			diff.SettingsChanged = true
+		}
+
+		var moved []string
+		users := make([]domain.User, 0, len(team.Members))
+		for _, member := range team.Members {
+			user, err := s.userRepo.GetByID(ctx, member.UserID)
+			if err != nil && !errors.Is(err, domain.ErrNotFound) {
+				return err
+			}
+
+			// members moving in from another team are deactivated
+			// too, their reviews are then reassigned only once
+			deactivated := err == nil && user.IsActive && !member.IsActive
+			if deactivated {
+				diff.Deactivated = append(diff.Deactivated, member.UserID)
+			}
+
+			switch {
+			case err != nil:
+				diff.Added = append(diff.Added, member.UserID)
+			case user.TeamName != team.Name:
+				diff.Added = append(diff.Added, member.UserID)
+				if user.TeamName != "" && !deactivated {
+					moved = append(moved, member.UserID)
+				}
+			case user.Username != member.Username ||
+				user.IsActive != member.IsActive ||
+				user.MaxOpenReviews != member.MaxOpenReviews:
+				diff.Updated = append(diff.Updated, member.UserID)
+			}
+
+			users = append(users, domain.User{
+				ID:             member.UserID,
+				Username:       member.Username,
+				TeamName:       team.Name,
+				IsActive:       member.IsActive,
+				MaxOpenReviews: member.MaxOpenReviews,
+			})
+		}
+		if err := s.userRepo.UpsertUsers(ctx, users); err != nil {
+			return err
+		}
+
+		listed := memberIDs(team.Members)
+		var unlistedIDs []string
+		for _, member := range current.Members {
+			if slices.Contains(listed, member.UserID) {
+				continue
+			}
+			switch {
+			case unlisted == domain.UnlistedRemove:
+				unlistedIDs = append(unlistedIDs, member.UserID)
+				diff.Removed = append(diff.Removed, member.UserID)
+			case member.IsActive:
+				unlistedIDs = append(unlistedIDs, member.UserID)
+				diff.Deactivated = append(diff.Deactivated, member.UserID)
+			}
+		}
+		if len(unlistedIDs) > 0 {
+			if unlisted == domain.UnlistedRemove {
+				_, err = s.userRepo.SetTeamForUsers(ctx, unlistedIDs, "")
+			} else {
+				_, err = s.userRepo.SetIsActiveForUsers(ctx, unlistedIDs, false)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, id := range diff.Deactivated {
+			reassignments, err := s.reassigner.ReassignAllFor(ctx, id)
+			if err != nil {
+				return err
+			}
+			diff.Reassignments = append(diff.Reassignments, reassignments...)
+		}
+
+		reassignments, err := s.applyReviewPolicy(ctx, slices.Concat(moved, diff.Removed), policy, nil)
+		if err != nil {
+			return err
+		}
+		diff.Reassignments = append(diff.Reassignments, reassignments...)
+
+		synced, err = s.teamRepo.GetTeamByName(ctx, team.Name)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, domain.EventTeamSynced, domain.AuditTeam, team.Name, before, synced)
+	})
+	if err != nil {
+		return nil, domain.TeamDiff{}, err
+	}
+
+	return synced, diff, nil
+}
+
+// applyReviewPolicy reassigns the open reviews of users who left
+// their team when the policy asks for it, never to any of except
+func (s *TeamService) applyReviewPolicy(
+	ctx context.Context,
+	userIDs []string,
+	policy domain.ReviewPolicy,
+	except []string,
+) ([]domain.Reassignment, error) {
+	reassignments := make([]domain.Reassignment, 0)
+	if policy != domain.ReviewsReassign {
+		return reassignments, nil
+	}
+
+	for _, id := range userIDs {
+		moved, err := s.reassigner.ReassignAllExcept(ctx, id, except)
+		if err != nil {
+			return nil, err
+		}
+		reassignments = append(reassignments, moved...)
+	}
+	return reassignments, nil
+}
+
+// leastLoaded returns the first candidate with the lowest load
+// that is neither excluded nor at capacity, 0 capacity means no limit
+func leastLoaded(
+	candidates []string,
+	load, capacity map[string]int,
+	exclude []string,
+) (string, bool) {
+	var (
+		best  string
+		found bool
+	)
+	for _, id := range candidates {
+		if slices.Contains(exclude, id) {
+			continue
+		}
+		if capacity[id] > 0 && load[id] >= capacity[id] {
+			continue
+		}
+		if !found || load[id] < load[best] {
+			best, found = id, true
+		}
+	}
+	return best, found
+}
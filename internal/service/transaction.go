@@ -0,0 +1,10 @@
+package service
+
+import "context"
+
+// TxManager runs several repository calls as a single unit of work.
+// Repository methods called with the context passed to fn take part
+// in the same transaction, nested WithinTx calls join the outer one.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -2,6 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
 
 	"github.com/ynsssss/pr-manager/internal/domain"
 )
@@ -9,24 +13,240 @@ import (
 // TODO: move
 type UserRepository interface {
 	GetByID(ctx context.Context, userID string) (domain.User, error)
+	// GetByIDs omits the users that don't exist
+	GetByIDs(ctx context.Context, userIDs []string) ([]domain.User, error)
+	// ListUsers returns up to filter.Limit users ordered by ID
+	ListUsers(ctx context.Context, filter domain.UserFilter) ([]domain.User, error)
 
 	SetIsActive(ctx context.Context, userID string, isActive bool) (domain.User, error)
+	SetIsActiveForUsers(ctx context.Context, userIDs []string, isActive bool) ([]domain.User, error)
+	SetMaxOpenReviews(ctx context.Context, userID string, maxOpenReviews int) (domain.User, error)
+	// SetTeamForUsers detaches the users from their team when teamName is empty
+	SetTeamForUsers(ctx context.Context, userIDs []string, teamName string) ([]domain.User, error)
 	// TODO: rename method
 	UpsertUsers(ctx context.Context, users []domain.User) error
+
+	AddOutOfOffice(ctx context.Context, ooo domain.OutOfOffice) (domain.OutOfOffice, error)
+	// GetOutOfOfficeUsers returns those of userIDs who are inside a window at the given time
+	GetOutOfOfficeUsers(ctx context.Context, userIDs []string, at time.Time) ([]string, error)
+	// GetStartedOutOfOffice returns the windows active at the given time
+	// whose users still have their reviews not reassigned
+	GetStartedOutOfOffice(ctx context.Context, at time.Time) ([]domain.OutOfOffice, error)
+	// MarkOutOfOfficeReassigned reports false if the window was already marked
+	MarkOutOfOfficeReassigned(ctx context.Context, id int64, at time.Time) (bool, error)
+}
+
+// ReviewReassigner moves the open reviews off a user
+type ReviewReassigner interface {
+	ReassignAllFor(ctx context.Context, reviewerID string) ([]domain.Reassignment, error)
+	// ReassignAllExcept never picks any of except as a replacement
+	ReassignAllExcept(ctx context.Context, reviewerID string, except []string) ([]domain.Reassignment, error)
 }
 
 type UserService struct {
-	repo UserRepository
+	repo       UserRepository
+	txManager  TxManager
+	reassigner ReviewReassigner
+	audit      AuditRecorder
 }
 
-func NewUserService(repo UserRepository) *UserService {
-	return &UserService{repo: repo}
+func NewUserService(
+	repo UserRepository,
+	txManager TxManager,
+	reassigner ReviewReassigner,
+	audit AuditRecorder,
+) *UserService {
+	return &UserService{
+		repo:       repo,
+		txManager:  txManager,
+		reassigner: reassigner,
+		audit:      audit,
+	}
 }
 
+// SetIsActive updates the flag. Deactivated users have their open
+// reviews reassigned in the same transaction, the outcome for
+// every review is returned.
 func (s *UserService) SetIsActive(
 	ctx context.Context,
 	userID string,
 	active bool,
+) (domain.User, []domain.Reassignment, error) {
+	var (
+		user          domain.User
+		reassignments = make([]domain.Reassignment, 0)
+	)
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.repo.GetByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		user, err = s.repo.SetIsActive(ctx, userID, active)
+		if err != nil {
+			return err
+		}
+		err = s.audit.Record(ctx, domain.EventUserActiveChanged, domain.AuditUser, userID, before, user)
+		if err != nil {
+			return err
+		}
+		if active {
+			return nil
+		}
+
+		reassignments, err = s.reassigner.ReassignAllFor(ctx, userID)
+		return err
+	})
+	if err != nil {
+		return domain.User{}, nil, err
+	}
+
+	return user, reassignments, nil
+}
+
+func (s *UserService) GetByID(ctx context.Context, userID string) (domain.User, error) {
+	return s.repo.GetByID(ctx, userID)
+}
+
+// ListUsers returns a page of users matching the filter
+func (s *UserService) ListUsers(ctx context.Context, filter domain.UserFilter) (domain.UserPage, error) {
+	if err := filter.Validate(); err != nil {
+		return domain.UserPage{}, err
+	}
+
+	users, next, err := listPage(
+		filter.Limit,
+		func(limit int) ([]domain.User, error) {
+			filter.Limit = limit
+			return s.repo.ListUsers(ctx, filter)
+		},
+		func(last domain.User) domain.Cursor {
+			return domain.Cursor{ID: last.ID}
+		},
+	)
+	if err != nil {
+		return domain.UserPage{}, err
+	}
+
+	return domain.UserPage{Users: users, NextCursor: next}, nil
+}
+
+// SetMaxOpenReviews sets the capacity of the user, 0 falls back
+// to the team default. Reviews above the new capacity are kept.
+func (s *UserService) SetMaxOpenReviews(
+	ctx context.Context,
+	userID string,
+	maxOpenReviews int,
 ) (domain.User, error) {
-	return s.repo.SetIsActive(ctx, userID, active)
+	if maxOpenReviews < 0 {
+		return domain.User{}, domain.ErrNegativeMaxOpenReviews
+	}
+
+	var user domain.User
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.repo.GetByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		user, err = s.repo.SetMaxOpenReviews(ctx, userID, maxOpenReviews)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, domain.EventUserCapacityChanged, domain.AuditUser, userID, before, user)
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	return user, nil
+}
+
+// AddOutOfOffice schedules a window when the user gets no reviews.
+// Reviews the user already has are moved away by RunOutOfOfficeJob
+// once the window starts.
+func (s *UserService) AddOutOfOffice(
+	ctx context.Context,
+	ooo domain.OutOfOffice,
+) (domain.OutOfOffice, error) {
+	if err := ooo.Validate(); err != nil {
+		return domain.OutOfOffice{}, err
+	}
+
+	var created domain.OutOfOffice
+	err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.repo.GetByID(ctx, ooo.UserID); err != nil {
+			return err
+		}
+
+		var err error
+		created, err = s.repo.AddOutOfOffice(ctx, ooo)
+		if err != nil {
+			return err
+		}
+		return s.audit.Record(ctx, domain.EventUserOutOfOfficeAdded, domain.AuditUser, ooo.UserID, nil, created)
+	})
+	if err != nil {
+		return domain.OutOfOffice{}, err
+	}
+
+	return created, nil
+}
+
+// ReassignStartedOutOfOffice reassigns the open reviews of users
+// whose out of office window has started. Every window is handled
+// in its own transaction, so one failure doesn't block the rest.
+func (s *UserService) ReassignStartedOutOfOffice(ctx context.Context) ([]domain.Reassignment, error) {
+	ctx = WithActor(ctx, domain.SystemActor)
+
+	now := time.Now()
+	windows, err := s.repo.GetStartedOutOfOffice(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	reassignments := make([]domain.Reassignment, 0)
+	var errs []error
+	for _, ooo := range windows {
+		err := s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+			// another instance may have handled the window already
+			marked, err := s.repo.MarkOutOfOfficeReassigned(ctx, ooo.ID, now)
+			if err != nil || !marked {
+				return err
+			}
+
+			moved, err := s.reassigner.ReassignAllFor(ctx, ooo.UserID)
+			if err != nil {
+				return err
+			}
+			reassignments = append(reassignments, moved...)
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("out of office %d: %w", ooo.ID, err))
+		}
+	}
+
+	return reassignments, errors.Join(errs...)
+}
+
+// RunOutOfOfficeJob calls ReassignStartedOutOfOffice every interval
+// until ctx is done
+func (s *UserService) RunOutOfOfficeJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reassignments, err := s.ReassignStartedOutOfOffice(ctx)
+		if err != nil {
+			log.Printf("out of office job: %v", err)
+		}
+		if len(reassignments) > 0 {
+			log.Printf("out of office job: %d reviews reassigned", len(reassignments))
+		}
+	}
 }
@@ -0,0 +1,4 @@
+CREATE TABLE team_rotations (
+    team_name TEXT PRIMARY KEY REFERENCES teams(team_name),
+    last_user_id TEXT NOT NULL -- last reviewer picked by round-robin
+);
@@ -0,0 +1,7 @@
+CREATE TABLE team_settings (
+    team_name TEXT PRIMARY KEY REFERENCES teams(team_name),
+    min_reviewers INT NOT NULL DEFAULT 0 CHECK (min_reviewers >= 0),
+    max_reviewers INT NOT NULL DEFAULT 2 CHECK (max_reviewers >= min_reviewers)
+);
+
+INSERT INTO team_settings (team_name) SELECT team_name FROM teams;
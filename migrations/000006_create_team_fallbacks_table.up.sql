@@ -0,0 +1,7 @@
+CREATE TABLE team_fallbacks (
+    team_name TEXT NOT NULL REFERENCES teams(team_name),
+    fallback_team_name TEXT NOT NULL REFERENCES teams(team_name),
+    position INT NOT NULL, -- order in which fallback teams are asked
+    PRIMARY KEY (team_name, fallback_team_name),
+    CHECK (team_name <> fallback_team_name)
+);
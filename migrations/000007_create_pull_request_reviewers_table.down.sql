@@ -0,0 +1,12 @@
+ALTER TABLE pull_requests ADD COLUMN assigned_reviewers TEXT[];
+
+UPDATE pull_requests pr
+   SET assigned_reviewers = ARRAY(
+       SELECT r.user_id
+         FROM pull_request_reviewers r
+        WHERE r.pull_request_id = pr.pull_request_id
+          AND r.state = 'ASSIGNED'
+        ORDER BY r.assigned_at, r.user_id
+   );
+
+DROP TABLE IF EXISTS pull_request_reviewers;
@@ -0,0 +1,25 @@
+CREATE TABLE pull_request_reviewers (
+    pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id),
+    user_id TEXT NOT NULL REFERENCES users(user_id),
+    assigned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    state TEXT NOT NULL DEFAULT 'ASSIGNED', -- ASSIGNED or UNASSIGNED
+    PRIMARY KEY (pull_request_id, user_id)
+);
+
+CREATE INDEX pull_request_reviewers_assigned_user_idx
+    ON pull_request_reviewers (user_id, pull_request_id)
+    WHERE state = 'ASSIGNED';
+
+-- reviewers are listed in the order of assignment, the array
+-- position is kept by spacing assigned_at by a microsecond
+INSERT INTO pull_request_reviewers (pull_request_id, user_id, assigned_at)
+SELECT DISTINCT ON (pr.pull_request_id, r.user_id)
+       pr.pull_request_id,
+       r.user_id,
+       pr.created_at + r.position * INTERVAL '1 microsecond'
+  FROM pull_requests pr
+ CROSS JOIN LATERAL unnest(pr.assigned_reviewers) WITH ORDINALITY AS r(user_id, position)
+  JOIN users u ON u.user_id = r.user_id
+ ORDER BY pr.pull_request_id, r.user_id, r.position;
+
+ALTER TABLE pull_requests DROP COLUMN assigned_reviewers;
@@ -0,0 +1,10 @@
+CREATE TABLE pull_request_reviews (
+    review_id BIGSERIAL PRIMARY KEY,
+    pull_request_id TEXT NOT NULL REFERENCES pull_requests(pull_request_id),
+    reviewer_id TEXT NOT NULL REFERENCES users(user_id),
+    verdict TEXT NOT NULL, -- APPROVED, CHANGES_REQUESTED or COMMENTED
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX pull_request_reviews_pull_request_idx
+    ON pull_request_reviews (pull_request_id, review_id);
@@ -0,0 +1,5 @@
+ALTER TABLE team_settings
+    ADD COLUMN required_approvals INT NOT NULL DEFAULT 0 CHECK (required_approvals >= 0);
+
+ALTER TABLE pull_requests
+    ADD COLUMN merge_forced_by TEXT REFERENCES users(user_id); -- set when the merge policy was bypassed
@@ -0,0 +1,14 @@
+CREATE TABLE user_out_of_office (
+    out_of_office_id BIGSERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(user_id),
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    reassigned_at TIMESTAMPTZ, -- set once the user's reviews were moved away
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX user_out_of_office_user_idx ON user_out_of_office (user_id, ends_at);
+
+CREATE INDEX user_out_of_office_pending_idx
+    ON user_out_of_office (starts_at)
+    WHERE reassigned_at IS NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE users
+    ADD COLUMN max_open_reviews INT NOT NULL DEFAULT 0 CHECK (max_open_reviews >= 0);
+
+ALTER TABLE team_settings
+    ADD COLUMN default_max_open_reviews INT NOT NULL DEFAULT 0 CHECK (default_max_open_reviews >= 0),
+    ADD COLUMN capacity_policy TEXT NOT NULL DEFAULT 'ASSIGN_FEWER'
+        CHECK (capacity_policy IN ('ASSIGN_FEWER', 'FAIL'));
@@ -0,0 +1,2 @@
+-- users removed from their team keep their history without a team
+ALTER TABLE users ALTER COLUMN team_name DROP NOT NULL;
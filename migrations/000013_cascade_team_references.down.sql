@@ -0,0 +1,22 @@
+ALTER TABLE team_fallbacks
+    DROP CONSTRAINT team_fallbacks_fallback_team_name_fkey,
+    ADD CONSTRAINT team_fallbacks_fallback_team_name_fkey FOREIGN KEY (fallback_team_name)
+        REFERENCES teams(team_name),
+    DROP CONSTRAINT team_fallbacks_team_name_fkey,
+    ADD CONSTRAINT team_fallbacks_team_name_fkey FOREIGN KEY (team_name)
+        REFERENCES teams(team_name);
+
+ALTER TABLE team_rotations
+    DROP CONSTRAINT team_rotations_team_name_fkey,
+    ADD CONSTRAINT team_rotations_team_name_fkey FOREIGN KEY (team_name)
+        REFERENCES teams(team_name);
+
+ALTER TABLE team_settings
+    DROP CONSTRAINT team_settings_team_name_fkey,
+    ADD CONSTRAINT team_settings_team_name_fkey FOREIGN KEY (team_name)
+        REFERENCES teams(team_name);
+
+ALTER TABLE users
+    DROP CONSTRAINT users_team_name_fkey,
+    ADD CONSTRAINT users_team_name_fkey FOREIGN KEY (team_name)
+        REFERENCES teams(team_name);
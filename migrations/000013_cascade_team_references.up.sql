@@ -0,0 +1,24 @@
+-- renaming a team updates every row referring to it,
+-- deleting one drops what it owns and detaches its members
+ALTER TABLE users
+    DROP CONSTRAINT users_team_name_fkey,
+    ADD CONSTRAINT users_team_name_fkey FOREIGN KEY (team_name)
+        REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE SET NULL;
+
+ALTER TABLE team_settings
+    DROP CONSTRAINT team_settings_team_name_fkey,
+    ADD CONSTRAINT team_settings_team_name_fkey FOREIGN KEY (team_name)
+        REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE team_rotations
+    DROP CONSTRAINT team_rotations_team_name_fkey,
+    ADD CONSTRAINT team_rotations_team_name_fkey FOREIGN KEY (team_name)
+        REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE team_fallbacks
+    DROP CONSTRAINT team_fallbacks_team_name_fkey,
+    ADD CONSTRAINT team_fallbacks_team_name_fkey FOREIGN KEY (team_name)
+        REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+    DROP CONSTRAINT team_fallbacks_fallback_team_name_fkey,
+    ADD CONSTRAINT team_fallbacks_fallback_team_name_fkey FOREIGN KEY (fallback_team_name)
+        REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE;
@@ -0,0 +1,6 @@
+ALTER TABLE teams
+    ADD COLUMN parent_team_name TEXT
+        REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE SET NULL,
+    ADD CHECK (parent_team_name <> team_name);
+
+CREATE INDEX teams_parent_team_name_idx ON teams (parent_team_name);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS users_team_name_idx;
+DROP INDEX IF EXISTS pull_requests_merged_idx;
+DROP INDEX IF EXISTS pull_requests_author_created_idx;
+DROP INDEX IF EXISTS pull_requests_status_created_idx;
+DROP INDEX IF EXISTS pull_requests_created_idx;
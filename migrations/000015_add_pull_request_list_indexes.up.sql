@@ -0,0 +1,15 @@
+-- keyset pagination of /pullRequest/list walks (created_at, pull_request_id) backwards
+CREATE INDEX pull_requests_created_idx
+    ON pull_requests (created_at DESC, pull_request_id DESC);
+
+CREATE INDEX pull_requests_status_created_idx
+    ON pull_requests (status, created_at DESC, pull_request_id DESC);
+
+CREATE INDEX pull_requests_author_created_idx
+    ON pull_requests (author_id, created_at DESC, pull_request_id DESC);
+
+CREATE INDEX pull_requests_merged_idx
+    ON pull_requests (merged_at)
+    WHERE merged_at IS NOT NULL;
+
+CREATE INDEX users_team_name_idx ON users (team_name);
@@ -0,0 +1,2 @@
+-- /users/list matches lower(username) LIKE 'prefix%'
+CREATE INDEX users_username_lower_idx ON users (lower(username) text_pattern_ops);
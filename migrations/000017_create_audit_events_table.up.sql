@@ -0,0 +1,24 @@
+CREATE TABLE audit_events (
+    audit_event_id BIGSERIAL PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    before JSONB, -- NULL for created entities
+    after JSONB,  -- NULL for deleted entities
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_events_entity_idx ON audit_events (entity_type, entity_id, audit_event_id);
+
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();